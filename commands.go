@@ -0,0 +1,347 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/sgaunet/awslogcheck/internal/app"
+	"github.com/sgaunet/awslogcheck/internal/configapp"
+	"github.com/sgaunet/awslogcheck/internal/logsource"
+	"github.com/sgaunet/awslogcheck/internal/rules"
+)
+
+const (
+	exitUsage            = 2
+	defaultRuleStatsDays = 30
+)
+
+// runCommand runs the command given after the options, and returns the exit code.
+func runCommand(args []string, configFilename, ssoProfile string, appLog *slog.Logger) int {
+	switch args[0] {
+	case "tail":
+		return runTailCommand(configFilename, ssoProfile, appLog)
+	case "lambda":
+		return runLambdaCommand(configFilename, appLog)
+	case "check":
+		return runCheckCommand(args[1:], configFilename, ssoProfile, appLog)
+	case "record":
+		return runRecordCommand(args[1:], configFilename, ssoProfile, appLog)
+	case "replay":
+		return runReplayCommand(args[1:], configFilename, ssoProfile, appLog)
+	case "rules":
+		configApp := loadConfiguration(configFilename, appLog)
+		return runRulesCommand(args[1:], configApp, ssoProfile, initTrace(configApp.DebugLevel))
+	case "lint-rules":
+		return runLintRulesCommand(args[1:], configFilename, appLog)
+	default:
+		fmt.Fprintf(os.Stderr, "ERROR: unknown command %s\n", args[0])
+		return exitUsage
+	}
+}
+
+// loadStartupRules syncs the rule sources and loads the rules of a, as at the startup of
+// the daemon. Errors are logged, and false is returned.
+func loadStartupRules(ctx context.Context, a *app.App, appLog *slog.Logger) bool {
+	if _, err := a.SyncRuleSources(ctx); err != nil {
+		appLog.Error("Cannot sync rule sources", slog.String("error", err.Error()))
+		return false
+	}
+	if err := a.LoadStartupRules(); err != nil {
+		appLog.Error("Cannot load rules", slog.String("error", err.Error()))
+		return false
+	}
+	return true
+}
+
+// ruleSourcesAWSConfig returns the AWS configuration needed to sync the rule sources, if any.
+func ruleSourcesAWSConfig(ssoProfile string, configApp configapp.AppConfig, appLog *slog.Logger) aws.Config {
+	if !configApp.HasRuleSources() {
+		return aws.Config{}
+	}
+	setupAWSConfig(ssoProfile, configApp, appLog)
+	return awsCfg
+}
+
+// runTailCommand runs "tail": the rules are applied to the events as they are ingested,
+// until SIGINT or SIGTERM.
+func runTailCommand(configFilename, ssoProfile string, appLog *slog.Logger) int {
+	configApp := loadConfiguration(configFilename, appLog)
+	appLog = initTrace(configApp.DebugLevel)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	setupAWSConfig(ssoProfile, configApp, appLog)
+	tailApp := app.New(ctx, configApp, awsCfg, lastPeriodSeconds, appLog)
+	if !loadStartupRules(ctx, tailApp, appLog) {
+		return 1
+	}
+	if err := tailApp.Tail(ctx); err != nil {
+		appLog.Error("Live tail failed", slog.String("error", err.Error()))
+		return 1
+	}
+	return 0
+}
+
+// runLambdaCommand runs "lambda": the program is the handler of a Lambda function, checking
+// the events sent by CloudWatch Logs subscription filters.
+func runLambdaCommand(configFilename string, appLog *slog.Logger) int {
+	configApp := loadConfiguration(configFilename, appLog)
+	appLog = initTrace(configApp.DebugLevel)
+	ctx := context.Background()
+
+	setupAWSConfig("", configApp, appLog)
+	lambdaApp := app.New(ctx, configApp, awsCfg, lastPeriodSeconds, appLog)
+	if !loadStartupRules(ctx, lambdaApp, appLog) {
+		return 1
+	}
+	lambda.Start(lambdaApp.HandleSubscription) // Does not return
+	return 0
+}
+
+// runCheckCommand runs "check [-format f] [-send] input...": the rules are applied to files,
+// the standard input ("-") or CloudWatch Logs exports to S3 (s3://bucket/prefix), and the
+// report is printed, or sent by mail. The exit code is 1 if anything is reported, to be used in CI.
+func runCheckCommand(args []string, configFilename, ssoProfile string, appLog *slog.Logger) int {
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+	format := flags.String("format", "",
+		"Format of the inputs: jsonl, filter-log-events, export or text (from the extension by default)")
+	send := flags.Bool("send", false, "Send the report by mail instead of printing it")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintf(os.Stderr, "usage: awslogcheck -c cfg.yml check [-format f] [-send] input...\n")
+		return exitUsage
+	}
+	configApp := loadConfiguration(configFilename, appLog)
+	appLog = initTrace(configApp.DebugLevel)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// AWS is only needed to read the exports, and to sync the rule sources
+	cfg := aws.Config{}
+	if configApp.HasRuleSources() ||
+		slices.ContainsFunc(flags.Args(), func(input string) bool { return strings.HasPrefix(input, "s3://") }) {
+		setupAWSConfig(ssoProfile, configApp, appLog)
+		cfg = awsCfg
+	}
+	checkApp := app.New(ctx, configApp, cfg, lastPeriodSeconds, appLog)
+	if !loadStartupRules(ctx, checkApp, appLog) {
+		return 1
+	}
+
+	sources := make([]logsource.Source, 0, flags.NArg())
+	for _, input := range flags.Args() {
+		source, err := checkApp.NewSource(input, *format)
+		if err != nil {
+			appLog.Error("Cannot open log source", slog.String("input", input), slog.String("error", err.Error()))
+			return exitUsage
+		}
+		sources = append(sources, source)
+	}
+	var report io.Writer = os.Stdout
+	if *send {
+		report = nil
+	}
+	printed, err := checkApp.CheckSources(ctx, sources, report)
+	if err != nil {
+		appLog.Error("Check failed", slog.String("error", err.Error()))
+		return 1
+	}
+	if printed > 0 {
+		fmt.Fprintf(os.Stderr, "%d line(s) reported\n", printed)
+		return 1
+	}
+	return 0
+}
+
+// runRecordCommand runs "record [-start t] [-end t] [-o file]": the pages of FilterLogEvents of
+// the log groups are saved for "replay", by default for the window of the hourly check.
+func runRecordCommand(args []string, configFilename, ssoProfile string, appLog *slog.Logger) int {
+	flags := flag.NewFlagSet("record", flag.ContinueOnError)
+	start := flags.String("start", "", "Start of the window, RFC3339 (last hour by default)")
+	end := flags.String("end", "", "End of the window, RFC3339 (last hour by default)")
+	output := flags.String("o", "", "Recording file (standard output by default)")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	configApp := loadConfiguration(configFilename, appLog)
+	appLog = initTrace(configApp.DebugLevel)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	setupAWSConfig(ssoProfile, configApp, appLog)
+	recordApp := app.New(ctx, configApp, awsCfg, lastPeriodSeconds, appLog)
+	minTimeStampInMs, maxTimeStampInMs, err := recordApp.GetTimeStampMsRangeofLastHour()
+	if err != nil {
+		appLog.Error("Cannot compute the window", slog.String("error", err.Error()))
+		return 1
+	}
+	for _, bound := range []struct {
+		value string
+		ms    *int64
+	}{{*start, &minTimeStampInMs}, {*end, &maxTimeStampInMs}} {
+		if bound.value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, bound.value)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: invalid time %s, expected RFC3339\n", bound.value)
+			return exitUsage
+		}
+		*bound.ms = t.UnixMilli()
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			appLog.Error("Cannot create recording", slog.String("error", err.Error()))
+			return 1
+		}
+		defer func() {
+			if err := f.Close(); err != nil {
+				appLog.Error("Cannot close recording", slog.String("error", err.Error()))
+			}
+		}()
+		w = f
+	}
+	if err := recordApp.Record(ctx, w, minTimeStampInMs, maxTimeStampInMs); err != nil {
+		appLog.Error("Record failed", slog.String("error", err.Error()))
+		return 1
+	}
+	return 0
+}
+
+// runReplayCommand runs "replay [-send] file": the rules are applied to a recording, as to
+// the log groups when it was recorded. The exit code is 1 if anything is reported.
+func runReplayCommand(args []string, configFilename, ssoProfile string, appLog *slog.Logger) int {
+	flags := flag.NewFlagSet("replay", flag.ContinueOnError)
+	send := flags.Bool("send", false, "Send the report by mail instead of printing it")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintf(os.Stderr, "usage: awslogcheck -c cfg.yml replay [-send] recording\n")
+		return exitUsage
+	}
+	configApp := loadConfiguration(configFilename, appLog)
+	appLog = initTrace(configApp.DebugLevel)
+	ctx := context.Background()
+
+	replayApp := app.New(ctx, configApp, ruleSourcesAWSConfig(ssoProfile, configApp, appLog), lastPeriodSeconds, appLog)
+	if !loadStartupRules(ctx, replayApp, appLog) {
+		return 1
+	}
+	// #nosec G304 - the recording to replay is given on the command line
+	recording, err := os.Open(flags.Arg(0))
+	if err != nil {
+		appLog.Error("Cannot open recording", slog.String("error", err.Error()))
+		return 1
+	}
+	defer func() { _ = recording.Close() }()
+
+	var report io.Writer = os.Stdout
+	if *send {
+		report = nil
+	}
+	printed, err := replayApp.Replay(ctx, recording, report)
+	if err != nil {
+		appLog.Error("Replay failed", slog.String("error", err.Error()))
+		return 1
+	}
+	if printed > 0 {
+		fmt.Fprintf(os.Stderr, "%d line(s) reported\n", printed)
+		return 1
+	}
+	return 0
+}
+
+// runRulesCommand runs "rules stats [-days N]".
+func runRulesCommand(args []string, configApp configapp.AppConfig, ssoProfile string, appLog *slog.Logger) int {
+	if len(args) == 0 || args[0] != "stats" {
+		fmt.Fprintf(os.Stderr, "usage: awslogcheck -c cfg.yml rules stats [-days N]\n")
+		return exitUsage
+	}
+	flags := flag.NewFlagSet("rules stats", flag.ContinueOnError)
+	days := flags.Int("days", defaultRuleStatsDays, "Period of the statistics, in days")
+	if err := flags.Parse(args[1:]); err != nil {
+		return exitUsage
+	}
+
+	ctx := context.Background()
+	statsApp := app.New(ctx, configApp, ruleSourcesAWSConfig(ssoProfile, configApp, appLog), lastPeriodSeconds, appLog)
+	if !loadStartupRules(ctx, statsApp, appLog) {
+		return 1
+	}
+	if err := statsApp.WriteRuleStats(os.Stdout, *days, time.Now()); err != nil {
+		appLog.Error("Cannot print rule statistics", slog.String("error", err.Error()))
+		return 1
+	}
+	return 0
+}
+
+// runLintRulesCommand runs "lint-rules [-logcheck level] [rulesdir]".
+// Without rulesdir, the rules directory of the configuration file is checked.
+// The exit code is 1 if anything is found, to be used in CI.
+func runLintRulesCommand(args []string, configFilename string, appLog *slog.Logger) int {
+	flags := flag.NewFlagSet("lint-rules", flag.ContinueOnError)
+	logcheckLevel := flags.String("logcheck", "", "Check a logcheck rules directory of the given level")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	var configApp configapp.AppConfig
+	if configFilename != "" {
+		configApp = loadConfiguration(configFilename, appLog)
+	}
+	if *logcheckLevel != "" {
+		configApp.RulesFormat = configapp.RulesFormatLogcheck
+		configApp.LogcheckLevel = *logcheckLevel
+	}
+	rulesDir := flags.Arg(0)
+	if rulesDir == "" {
+		if configFilename == "" {
+			fmt.Fprintf(os.Stderr, "usage: awslogcheck [-c cfg.yml] lint-rules [-logcheck level] [rulesdir]\n")
+			return exitUsage
+		}
+		var err error
+		if rulesDir, err = configApp.GetRulesDir(); err != nil {
+			appLog.Error("Cannot find rules directory", slog.String("error", err.Error()))
+			return 1
+		}
+	}
+
+	var (
+		findings []rules.Finding
+		err      error
+	)
+	if configApp.IsLogcheckFormat() {
+		findings, err = rules.LintLogcheckDir(rulesDir, configApp.LogcheckLevel)
+	} else {
+		findings, err = rules.LintDir(rulesDir)
+	}
+	if err != nil {
+		appLog.Error("Cannot load rules", slog.String("error", err.Error()))
+		return 1
+	}
+	for _, finding := range findings {
+		fmt.Println(finding.String())
+	}
+	if len(findings) > 0 {
+		fmt.Fprintf(os.Stderr, "%d issue(s) found\n", len(findings))
+		return 1
+	}
+	return 0
+}
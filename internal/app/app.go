@@ -2,12 +2,10 @@
 package app
 
 import (
-	"bufio"
 	"context"
 	"fmt"
 	"log/slog"
 	"os"
-	"path/filepath"
 	"regexp"
 	"runtime"
 	"time"
@@ -23,7 +21,13 @@ import (
 type App struct {
 	cfg               configapp.AppConfig
 	awscfg            aws.Config
-	rules             []string
+	rules             ruleSet
+	skippedRules      int // Rules that could not be loaded or compiled
+	ignoreSelectors   []selector
+	skippedSelectors  int        // Ignore selectors that could not be parsed
+	multiline         *multiline // nil if multi-line events are not assembled
+	eventsInMemory    int        // Events of the current run not spilled to disk
+	spillThreshold    int        // Events in memory before the next spill of the largest streams
 	lastPeriodToWatch int
 	appLog            *slog.Logger
 	eventsRateLimit   *rate.Limiter
@@ -49,6 +53,8 @@ func New(_ context.Context, cfg configapp.AppConfig, awscfg aws.Config, lastPeri
 		eventsRateLimit:   rate.NewLimiter(rate.Limit(maxEventsAPICallPerSecond), maxEventsAPICallPerSecond),
 		logGroupRateLimit: rate.NewLimiter(rate.Limit(maxLogGroupAPICallPerSecond), maxLogGroupAPICallPerSecond),
 	}
+	app.ignoreSelectors = app.compileSelectors()
+	app.multiline = app.compileMultiline()
 	return &app
 }
 
@@ -57,51 +63,6 @@ func (a *App) GetLogger() *slog.Logger {
 	return a.appLog
 }
 
-// LoadRules loads regexp rules that will be used to ignore events (log).
-func (a *App) LoadRules() error {
-	rulesDir, err := a.cfg.GetRulesDir()
-	if err != nil {
-		return fmt.Errorf("failed to get rules directory: %w", err)
-	}
-	if rulesDir == "" {
-		return fmt.Errorf("%w", ErrNoRulesFolder)
-	}
-
-	_, err = os.Stat(rulesDir)
-	if err != nil {
-		return fmt.Errorf("failed to stat rules directory: %w", err)
-	}
-	err = filepath.Walk(rulesDir,
-		func(pathitem string, info os.FileInfo, err error) error {
-			if !info.IsDir() {
-				// #nosec G304 - pathitem is from filepath.Walk of trusted rules directory
-				ruleFile, err := os.Open(pathitem)
-				if err != nil {
-					return fmt.Errorf("failed to open rule file: %w", err)
-				}
-				defer func() {
-					if closeErr := ruleFile.Close(); closeErr != nil {
-						a.appLog.Error("Failed to close rule file", slog.String("error", closeErr.Error()))
-					}
-				}()
-				scanner := bufio.NewScanner(ruleFile)
-				scanner.Split(bufio.ScanLines)
-
-				for scanner.Scan() {
-					// fmt.Println("====", scanner.Text())
-					a.rules = append(a.rules, scanner.Text())
-				}
-			}
-			return err
-		})
-
-	if err != nil {
-		return fmt.Errorf("failed to walk rules directory: %w", err)
-	}
-	return nil
-}
-
-
 // PrintMemoryStats prints memory statistics periodically until stopped.
 func (a *App) PrintMemoryStats(stop <-chan interface{}) {
 	for {
@@ -189,17 +150,3 @@ func (a *App) isContainerIgnored(containerToCheck string) bool {
 	}
 	return false
 }
-
-func (a *App) isLineMatchWithOneRule(line string, rules []string) bool {
-	for _, rule := range rules {
-		r, err := regexp.Compile(rule)
-		if err != nil {
-			a.appLog.Error("rule is incorrect", slog.String("rule", rule))
-		} else if r.MatchString(line) {
-			a.appLog.Debug("Rule match", slog.String("rule", rule), slog.String("line", line))
-			return true
-		}
-	}
-	a.appLog.Debug("Line matches no rules", slog.String("line", line))
-	return false
-}
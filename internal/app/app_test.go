@@ -1,11 +1,17 @@
 package app_test
 
 import (
+	"archive/tar"
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -429,4 +435,202 @@ func BenchmarkSendReport(b *testing.B) {
 		// This will fail on mail sending but we're benchmarking file reading
 		_ = app.SendReport(tmpFile.Name())
 	}
-}
\ No newline at end of file
+}
+
+// TestValidateRules tests that incorrect rules prevent a reload
+func TestValidateRules(t *testing.T) {
+	tmpDir := t.TempDir()
+	ruleFile := filepath.Join(tmpDir, "app.rule")
+	if err := os.WriteFile(ruleFile, []byte("^INFO:\n"), 0644); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	application := createTestApp(configapp.AppConfig{RulesDir: tmpDir})
+	if err := application.LoadRules(); err != nil {
+		t.Fatalf("LoadRules returned error: %v", err)
+	}
+	if err := application.ValidateRules(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if err := os.WriteFile(ruleFile, []byte("^INFO:\n[broken\n"), 0644); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	application = createTestApp(configapp.AppConfig{RulesDir: tmpDir})
+	if err := application.LoadRules(); err != nil {
+		t.Fatalf("LoadRules returned error: %v", err)
+	}
+	if err := application.ValidateRules(); !errors.Is(err, app.ErrInvalidRules) {
+		t.Errorf("Expected ErrInvalidRules, got %v", err)
+	}
+}
+
+// TestValidateRulesFieldProblem tests that a field rule which does not parse prevents a reload
+func TestValidateRulesFieldProblem(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "app.rule"), []byte("^INFO:\n#!fields\nstatus < abc\n"), 0644); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	application := createTestApp(configapp.AppConfig{RulesDir: tmpDir})
+	if err := application.LoadRules(); err != nil {
+		t.Fatalf("LoadRules returned error: %v", err)
+	}
+	if err := application.ValidateRules(); !errors.Is(err, app.ErrInvalidRules) {
+		t.Errorf("Expected ErrInvalidRules, got %v", err)
+	}
+}
+
+// TestValidateRulesUntranslatable tests that logcheck rules without RE2 equivalent are
+// skipped, but don't prevent a reload, unlike malformed ones
+func TestValidateRulesUntranslatable(t *testing.T) {
+	tests := []struct {
+		name      string
+		rules     string
+		expectErr error
+	}{
+		{name: "Backreference", rules: "(x)\\1\n^INFO:\n"},
+		{name: "Unterminated bracket", rules: "[broken\n^INFO:\n", expectErr: app.ErrInvalidRules},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			ignoreDir := filepath.Join(tmpDir, "ignore.d.server")
+			if err := os.Mkdir(ignoreDir, 0755); err != nil {
+				t.Fatalf("Setup failed: %v", err)
+			}
+			if err := os.WriteFile(filepath.Join(ignoreDir, "app"), []byte(tt.rules), 0644); err != nil {
+				t.Fatalf("Setup failed: %v", err)
+			}
+			application := createTestApp(configapp.AppConfig{
+				RulesDir:    tmpDir,
+				RulesFormat: configapp.RulesFormatLogcheck,
+			})
+			if err := application.LoadRules(); err != nil {
+				t.Fatalf("LoadRules returned error: %v", err)
+			}
+			if err := application.ValidateRules(); !errors.Is(err, tt.expectErr) {
+				t.Errorf("Expected %v, got %v", tt.expectErr, err)
+			}
+		})
+	}
+}
+
+// TestValidateRulesInvalidSelector tests that incorrect ignore selectors prevent a reload
+func TestValidateRulesInvalidSelector(t *testing.T) {
+	application := createTestApp(configapp.AppConfig{
+		SelectorsToIgnore: []string{"namespace=kube-system", "namespace"},
+	})
+	if err := application.ValidateRules(); !errors.Is(err, app.ErrInvalidSelector) {
+		t.Errorf("Expected ErrInvalidSelector, got %v", err)
+	}
+}
+
+// TestRuleSources tests the rules of a remote source, and the use of the cache when it is down
+func TestRuleSources(t *testing.T) {
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	content := "^INFO:\n"
+	if err := tw.WriteHeader(&tar.Header{Name: "app.rule", Mode: 0o644, Size: int64(len(content))}); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	if _, err := tw.Write([]byte(content)); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(archive.Bytes())
+	}))
+	cfg := configapp.AppConfig{
+		RuleSources:   []string{server.URL + "/rules.tar"},
+		RulesCacheDir: t.TempDir(),
+	}
+
+	application := createTestApp(cfg)
+	if err := application.LoadRules(); !errors.Is(err, app.ErrRuleSourceNotSynced) {
+		t.Errorf("Expected ErrRuleSourceNotSynced, got %v", err)
+	}
+	changed, err := application.SyncRuleSources(context.Background())
+	if err != nil || !changed {
+		t.Fatalf("SyncRuleSources returned %v, %v", changed, err)
+	}
+	if err := application.LoadSyncedRules(); err != nil {
+		t.Errorf("LoadSyncedRules returned error: %v", err)
+	}
+
+	server.Close()
+	application = createTestApp(cfg)
+	if _, err := application.SyncRuleSources(context.Background()); err != nil {
+		t.Errorf("The cached rules should be used when the source is down, got %v", err)
+	}
+	if err := application.LoadRules(); err != nil {
+		t.Errorf("LoadRules returned error: %v", err)
+	}
+}
+
+// TestRuleSourcesRejected tests that invalid rules of a source are not kept, and synced again
+func TestRuleSourcesRejected(t *testing.T) {
+	tarball := func(content string) []byte {
+		var archive bytes.Buffer
+		tw := tar.NewWriter(&archive)
+		if err := tw.WriteHeader(&tar.Header{Name: "app.rule", Mode: 0o644, Size: int64(len(content))}); err != nil {
+			t.Fatalf("Setup failed: %v", err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatalf("Setup failed: %v", err)
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatalf("Setup failed: %v", err)
+		}
+		return archive.Bytes()
+	}
+	var mu sync.Mutex
+	archive := tarball("^INFO:\n")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		_, _ = w.Write(archive)
+	}))
+	defer server.Close()
+	cfg := configapp.AppConfig{
+		RuleSources:   []string{server.URL + "/rules.tar"},
+		RulesCacheDir: t.TempDir(),
+	}
+	application := createTestApp(cfg)
+	if _, err := application.SyncRuleSources(context.Background()); err != nil {
+		t.Fatalf("SyncRuleSources returned error: %v", err)
+	}
+	if err := application.LoadSyncedRules(); err != nil {
+		t.Fatalf("LoadSyncedRules returned error: %v", err)
+	}
+
+	mu.Lock()
+	archive = tarball("^INFO:\n[broken\n")
+	mu.Unlock()
+	for range 2 {
+		application = createTestApp(cfg)
+		changed, err := application.SyncRuleSources(context.Background())
+		if err != nil || !changed {
+			t.Fatalf("SyncRuleSources returned %v, %v", changed, err)
+		}
+		if err := application.LoadSyncedRules(); !errors.Is(err, app.ErrInvalidRules) {
+			t.Errorf("Expected ErrInvalidRules, got %v", err)
+		}
+	}
+
+	// At startup, the incorrect rules are skipped but the new copy is not kept either
+	application = createTestApp(cfg)
+	if _, err := application.SyncRuleSources(context.Background()); err != nil {
+		t.Fatalf("SyncRuleSources returned error: %v", err)
+	}
+	if err := application.LoadStartupRules(); err != nil {
+		t.Errorf("LoadStartupRules returned error: %v", err)
+	}
+
+	// The rejected rules are not used after a restart
+	application = createTestApp(cfg)
+	if err := application.LoadSyncedRules(); err != nil {
+		t.Errorf("The previous rules should be kept, got %v", err)
+	}
+}
@@ -5,43 +5,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
-	"os"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
 )
 
-// Recursive function that will return if the groupName parameter has been found or not.
-func (a *App) findLogGroup(ctx context.Context, clientCloudwatchlogs *cloudwatchlogs.Client,
-	groupName string, nextToken string) bool {
-	var params cloudwatchlogs.DescribeLogGroupsInput
-	if len(nextToken) != 0 {
-		params.NextToken = &nextToken
-	}
-	if err := a.logGroupRateLimit.Wait(ctx); err != nil {
-		a.appLog.Error("Rate limit error", slog.String("error", err.Error()))
-		return false
-	}
-	res, err := clientCloudwatchlogs.DescribeLogGroups(ctx, &params)
-	if err != nil {
-		a.appLog.Error(err.Error())
-		os.Exit(1)
-	}
-	for _, i := range res.LogGroups {
-		fmt.Printf("## Parse Log Group Name : %s\n", *i.LogGroupName)
-		if *i.LogGroupName == groupName {
-			return true
-		}
-	}
-	if res.NextToken == nil {
-		// No token given, end of potential recursive call to parse the list of loggroups
-		return false
-	}
-	return a.findLogGroup(ctx, clientCloudwatchlogs, groupName, *res.NextToken)
-}
-
 const millisecondsMultiplier = 1000
 
 // streamEvents holds events grouped by log stream (like original behavior).
@@ -49,7 +20,14 @@ type streamEvents struct {
 	streamName          string
 	firstContainerInfo  containerInfo // Info from first non-ignored container encountered
 	events              []logEvent
-	hasIgnoredContainer bool // If true, skip this entire stream
+	hasIgnoredContainer bool                     // If true, skip this entire stream (ignorescope: stream)
+	pending             map[string]*pendingEvent // Multi-line events being assembled, by container
+	contextBefore       []logEvent               // Last ignored lines, context of the next reported one
+	contextAfter        int                      // Ignored lines still to keep after the last reported one
+	lastSection         reportSection            // Section of the last reported line
+	sectionCounts       map[reportSection]int    // Reported lines by section, context lines excluded
+	spill               *spillFile               // Events spilled to disk, nil if none
+	unspillable         bool                     // A spill failed, the events stay in memory
 }
 
 // containerInfo holds container metadata.
@@ -63,6 +41,8 @@ type containerInfo struct {
 type logEvent struct {
 	timestamp int64
 	message   string
+	section   reportSection
+	context   bool // Ignored line printed around the reported ones
 }
 
 // CloudWatchLogsFilterClient interface for testing.
@@ -81,12 +61,21 @@ func (a *App) parseAllEventsWithFilter(ctx context.Context,
 
 // parseAllEventsWithFilterClient is the testable version that takes an interface.
 func (a *App) parseAllEventsWithFilterClient(ctx context.Context, client CloudWatchLogsFilterClient,
+	groupName string, minTimeStamp int64, maxTimeStamp int64, chLogLines chan<- string) (int, error) {
+	return a.parseAllEventsWithWorkers(ctx, client, a.cfg.Fetch.Workers, groupName, minTimeStamp, maxTimeStamp,
+		chLogLines)
+}
+
+// parseAllEventsWithWorkers fetches the events with the given number of concurrent workers,
+// <=1 to fetch sequentially.
+func (a *App) parseAllEventsWithWorkers(ctx context.Context, client CloudWatchLogsFilterClient, workers int,
 	groupName string, minTimeStamp int64, maxTimeStamp int64, chLogLines chan<- string) (int, error) {
 	input := a.buildFilterLogEventsInput(groupName, minTimeStamp, maxTimeStamp)
-	streamGroups, eventCount, err := a.fetchAndProcessAllEvents(ctx, client, input)
+	streamGroups, eventCount, err := a.fetchAndProcessAllEvents(ctx, client, input, workers)
 	if err != nil {
 		return 0, err
 	}
+	defer a.releaseStreams(streamGroups)
 	return a.outputStreamEvents(streamGroups, chLogLines, eventCount)
 }
 
@@ -97,33 +86,76 @@ func (a *App) buildFilterLogEventsInput(groupName string, minTimeStamp,
 		slog.Int64("minTimeStamp", minTimeStamp),
 		slog.Int64("maxTimeStamp", maxTimeStamp))
 
-	return &cloudwatchlogs.FilterLogEventsInput{
+	input := &cloudwatchlogs.FilterLogEventsInput{
 		LogGroupName: &groupName,
 		StartTime:    &minTimeStamp,
 		EndTime:      &maxTimeStamp,
 		Interleaved:  &[]bool{true}[0], // Sort events from multiple streams by timestamp
 	}
+	if pattern := a.filterPattern(groupName); pattern != "" {
+		a.appLog.Debug("Filter pattern", slog.String("groupName", groupName), slog.String("filterPattern", pattern))
+		input.FilterPattern = &pattern
+	}
+	return input
 }
 
 func (a *App) fetchAndProcessAllEvents(ctx context.Context, client CloudWatchLogsFilterClient,
-	input *cloudwatchlogs.FilterLogEventsInput) (map[string]*streamEvents, int, error) {
+	input *cloudwatchlogs.FilterLogEventsInput, workers int) (map[string]*streamEvents, int, error) {
 	streamGroups := make(map[string]*streamEvents)
 	eventCount := 0
+	processPage := func(events []types.FilteredLogEvent) {
+		a.processEventsInPage(events, streamGroups, &eventCount)
+		if eventCount%1000 == 0 {
+			a.appLog.Debug("Processed events", slog.Int("eventCount", eventCount))
+		}
+	}
+
+	var pageCount int
+	var err error
+	if workers > 1 {
+		pageCount, err = a.fetchSlices(ctx, client, input, workers, processPage)
+	} else {
+		pageCount, err = a.fetchPages(ctx, client, input, func(events []types.FilteredLogEvent) error {
+			processPage(events)
+			return nil
+		})
+	}
+	if err != nil {
+		a.releaseStreams(streamGroups)
+		return nil, eventCount, err
+	}
+
+	if a.multiline != nil {
+		a.flushMultilineEvents(streamGroups)
+	}
+
+	a.appLog.Debug("Completed FilterLogEvents processing",
+		slog.Int("totalEvents", eventCount),
+		slog.Int("pages", pageCount),
+		slog.Int("streams", len(streamGroups)))
+	return streamGroups, eventCount, nil
+}
+
+// fetchPages calls fn with the events of every page of input, and returns the number of pages.
+func (a *App) fetchPages(ctx context.Context, client CloudWatchLogsFilterClient,
+	input *cloudwatchlogs.FilterLogEventsInput, fn func([]types.FilteredLogEvent) error) (int, error) {
 	pageCount := 0
 	var nextToken *string
 
 	for {
-		if err := a.checkContextAndRateLimit(ctx); err != nil {
-			return nil, eventCount, err
-		}
-
 		if nextToken != nil {
 			input.NextToken = nextToken
 		}
 
-		output, err := client.FilterLogEvents(ctx, input)
+		// A failed page is retried with the same token, the pages already processed are kept
+		var output *cloudwatchlogs.FilterLogEventsOutput
+		err := a.withRetry(ctx, "FilterLogEvents", a.eventsRateLimit, maxEventsAPICallPerSecond, func() error {
+			var err error
+			output, err = client.FilterLogEvents(ctx, input)
+			return err //nolint:wrapcheck // wrapped below, once retries are exhausted
+		})
 		if err != nil {
-			return nil, eventCount, fmt.Errorf("failed to filter log events: %w", err)
+			return pageCount, fmt.Errorf("failed to filter log events: %w", err)
 		}
 
 		pageCount++
@@ -133,10 +165,8 @@ func (a *App) fetchAndProcessAllEvents(ctx context.Context, client CloudWatchLog
 			break
 		}
 
-		a.processEventsInPage(output.Events, streamGroups, &eventCount)
-
-		if eventCount%1000 == 0 {
-			a.appLog.Debug("Processed events", slog.Int("eventCount", eventCount))
+		if err := fn(output.Events); err != nil {
+			return pageCount, err
 		}
 
 		nextToken = output.NextToken
@@ -144,25 +174,7 @@ func (a *App) fetchAndProcessAllEvents(ctx context.Context, client CloudWatchLog
 			break
 		}
 	}
-
-	a.appLog.Debug("Completed FilterLogEvents processing",
-		slog.Int("totalEvents", eventCount),
-		slog.Int("pages", pageCount),
-		slog.Int("streams", len(streamGroups)))
-	return streamGroups, eventCount, nil
-}
-
-func (a *App) checkContextAndRateLimit(ctx context.Context) error {
-	select {
-	case <-ctx.Done():
-		return fmt.Errorf("context cancelled: %w", ctx.Err())
-	default:
-	}
-
-	if err := a.eventsRateLimit.Wait(ctx); err != nil {
-		return fmt.Errorf("rate limit wait error: %w", err)
-	}
-	return nil
+	return pageCount, nil
 }
 
 func (a *App) processEventsInPage(events []types.FilteredLogEvent,
@@ -170,6 +182,7 @@ func (a *App) processEventsInPage(events []types.FilteredLogEvent,
 	for _, event := range events {
 		*eventCount++
 		a.processLogEvent(event, streamGroups)
+		a.spillLargestStreams(streamGroups)
 	}
 }
 
@@ -183,12 +196,24 @@ func (a *App) processLogEvent(event types.FilteredLogEvent, streamGroups map[str
 
 	streamName := *event.LogStreamName
 	stream := a.getOrCreateStream(streamName, streamGroups)
+	if a.multiline != nil {
+		a.addMultilineEvent(lineOfLog, stream, event)
+		return
+	}
+	a.processMessage(lineOfLog, stream, event)
+}
 
-	if a.isLineMatchWithOneRule(lineOfLog.Log, a.rules) {
+// processMessage classifies a message, and adds it to its stream if it must be reported.
+func (a *App) processMessage(lineOfLog fluentDockerLog, stream *streamEvents, event types.FilteredLogEvent) {
+	section := a.classifyLine(lineOfLog.Log)
+	if section == sectionIgnored {
+		if a.cfg.ContextLines > 0 {
+			a.keepContextLine(lineOfLog, stream, event)
+		}
 		return
 	}
 
-	a.processUnmatchedLogLine(lineOfLog, stream, event, streamName)
+	a.processUnmatchedLogLine(lineOfLog, stream, event, stream.streamName, section)
 }
 
 func (a *App) getOrCreateStream(streamName string, streamGroups map[string]*streamEvents) *streamEvents {
@@ -206,23 +231,67 @@ func (a *App) getOrCreateStream(streamName string, streamGroups map[string]*stre
 }
 
 func (a *App) processUnmatchedLogLine(lineOfLog fluentDockerLog, stream *streamEvents,
-	event types.FilteredLogEvent, streamName string) {
-	imageIgnored := a.isImageIgnored(lineOfLog.Kubernetes.ContainerImage)
-	containerIgnored := a.isContainerIgnored(lineOfLog.Kubernetes.ContainerName)
-
-	if imageIgnored || containerIgnored {
-		stream.hasIgnoredContainer = true
-		a.appLog.Debug("Stream marked as ignored",
+	event types.FilteredLogEvent, streamName string, section reportSection) {
+	ignored := a.isEventIgnored(lineOfLog.Kubernetes)
+	if ignored && section == sectionCritical {
+		// Alert rules are reported even for ignored containers, and their streams
+		if !a.cfg.IsIgnoredPerEvent() {
+			a.markStreamIgnored(stream, lineOfLog.Kubernetes)
+		}
+		a.addEventToStream(lineOfLog, stream, event, section)
+		return
+	}
+	if ignored && a.cfg.IsIgnoredPerEvent() {
+		a.appLog.Debug("Event of ignored container skipped",
 			slog.String("streamName", streamName),
 			slog.String("containerImage", lineOfLog.Kubernetes.ContainerImage),
 			slog.String("containerName", lineOfLog.Kubernetes.ContainerName))
 		return
 	}
+	if ignored {
+		a.markStreamIgnored(stream, lineOfLog.Kubernetes)
+		return
+	}
 
-	a.addEventToStream(lineOfLog, stream, event)
+	a.addEventToStream(lineOfLog, stream, event, section)
 }
 
-func (a *App) addEventToStream(lineOfLog fluentDockerLog, stream *streamEvents, event types.FilteredLogEvent) {
+// markStreamIgnored marks stream as having an ignored container: only its critical lines are
+// still reported.
+func (a *App) markStreamIgnored(stream *streamEvents, infos kubernetesInfos) {
+	if stream.hasIgnoredContainer {
+		return
+	}
+	// The other events of the stream are not reported, they are useless
+	kept := stream.events[:0]
+	for _, event := range stream.events {
+		if event.section == sectionCritical && !event.context {
+			kept = append(kept, event)
+		}
+	}
+	a.eventsInMemory -= len(stream.events) - len(kept)
+	stream.events = kept
+	if critical := stream.sectionCounts[sectionCritical]; critical > 0 {
+		stream.sectionCounts = map[reportSection]int{sectionCritical: critical}
+	} else {
+		stream.sectionCounts = nil
+	}
+	stream.hasIgnoredContainer = true
+	a.appLog.Debug("Stream marked as ignored",
+		slog.String("streamName", stream.streamName),
+		slog.String("containerImage", infos.ContainerImage),
+		slog.String("containerName", infos.ContainerName))
+}
+
+// isEventIgnored checks if the container of an event is ignored by the configuration.
+func (a *App) isEventIgnored(infos kubernetesInfos) bool {
+	return a.isImageIgnored(infos.ContainerImage) ||
+		a.isContainerIgnored(infos.ContainerName) ||
+		a.isSelectorIgnored(infos)
+}
+
+func (a *App) addEventToStream(lineOfLog fluentDockerLog, stream *streamEvents,
+	event types.FilteredLogEvent, section reportSection) {
 	if stream.firstContainerInfo.containerImage == "" {
 		stream.firstContainerInfo = containerInfo{
 			podName:        lineOfLog.Kubernetes.PodName,
@@ -231,25 +300,38 @@ func (a *App) addEventToStream(lineOfLog fluentDockerLog, stream *streamEvents,
 		}
 	}
 
-	stream.events = append(stream.events, logEvent{
+	if a.cfg.ContextLines > 0 {
+		a.addContextBefore(stream, section)
+	}
+	a.appendEvent(stream, logEvent{
 		timestamp: *event.Timestamp,
 		message:   lineOfLog.Log,
+		section:   section,
 	})
 }
 
 func (a *App) outputStreamEvents(streamGroups map[string]*streamEvents, chLogLines chan<- string, _ int) (int, error) {
-	streamKeys := a.getSortedStreamKeys(streamGroups)
+	streamKeys := a.getReportedStreamKeys(streamGroups)
+	sectionCounts := make(map[reportSection]int)
+	for _, streamKey := range streamKeys {
+		for section, count := range streamGroups[streamKey].sectionCounts {
+			sectionCounts[section] += count
+		}
+	}
+	// Section titles are only useful when something else than system events is reported
+	withTitles := len(sectionCounts) > 1 || sectionCounts[sectionSystem] == 0
 	cptLinePrinted := 0
 
-	for _, streamKey := range streamKeys {
-		stream := streamGroups[streamKey]
-		if stream.hasIgnoredContainer || len(stream.events) == 0 {
-			if stream.hasIgnoredContainer {
-				a.appLog.Debug("Skipping stream due to ignored containers", slog.String("streamKey", streamKey))
-			}
+	for _, section := range reportSections {
+		if sectionCounts[section] == 0 {
 			continue
 		}
-		cptLinePrinted += a.outputSingleStream(stream, chLogLines)
+		if withTitles {
+			chLogLines <- "<h2>" + section.title() + "</h2>\n"
+		}
+		for _, streamKey := range streamKeys {
+			cptLinePrinted += a.outputSingleStream(streamGroups[streamKey], section, chLogLines)
+		}
 	}
 
 	a.appLog.Debug("Output complete",
@@ -258,6 +340,23 @@ func (a *App) outputStreamEvents(streamGroups map[string]*streamEvents, chLogLin
 	return cptLinePrinted, nil
 }
 
+// getReportedStreamKeys returns the sorted keys of the streams having events to report.
+func (a *App) getReportedStreamKeys(streamGroups map[string]*streamEvents) []string {
+	streamKeys := make([]string, 0, len(streamGroups))
+	for _, streamKey := range a.getSortedStreamKeys(streamGroups) {
+		stream := streamGroups[streamKey]
+		if stream.hasIgnoredContainer && stream.sectionCounts[sectionCritical] == 0 {
+			a.appLog.Debug("Skipping stream due to ignored containers", slog.String("streamKey", streamKey))
+			continue
+		}
+		if len(stream.sectionCounts) == 0 {
+			continue
+		}
+		streamKeys = append(streamKeys, streamKey)
+	}
+	return streamKeys
+}
+
 func (a *App) getSortedStreamKeys(streamGroups map[string]*streamEvents) []string {
 	streamKeys := make([]string, 0, len(streamGroups))
 	for key := range streamGroups {
@@ -267,20 +366,45 @@ func (a *App) getSortedStreamKeys(streamGroups map[string]*streamEvents) []strin
 	return streamKeys
 }
 
-func (a *App) outputSingleStream(stream *streamEvents, chLogLines chan<- string) int {
-	chLogLines <- "<b>Parse stream</b> :" + stream.streamName + "<br>"
-	chLogLines <- "<b>Container Image</b> :" + stream.firstContainerInfo.containerImage + "<br>"
-	chLogLines <- "<b>Container Name</b> :" + stream.firstContainerInfo.containerName + "<br>"
-
-	sort.Slice(stream.events, func(i, j int) bool {
-		return stream.events[i].timestamp < stream.events[j].timestamp
-	})
-
-	for _, event := range stream.events {
+// outputSingleStream prints the events of stream belonging to section, sorted by timestamp.
+// It returns the number of reported lines, context lines excluded.
+func (a *App) outputSingleStream(stream *streamEvents, section reportSection, chLogLines chan<- string) int {
+	if stream.sectionCounts[section] == 0 {
+		return 0
+	}
+	cptLinePrinted := 0
+	headerPrinted := false
+	err := a.forEachEvent(stream, func(event logEvent) {
+		if event.section != section {
+			return
+		}
+		if !headerPrinted {
+			chLogLines <- "<b>Parse stream</b> :" + stream.streamName + "<br>"
+			chLogLines <- "<b>Container Image</b> :" + stream.firstContainerInfo.containerImage + "<br>"
+			chLogLines <- "<b>Container Name</b> :" + stream.firstContainerInfo.containerName + "<br>"
+			headerPrinted = true
+		}
 		timeT := time.Unix(event.timestamp/millisecondsMultiplier, 0).UTC()
-		chLogLines <- fmt.Sprintf("%s UTC: %s<br>\n", timeT.Format("2006-01-02 15:04:05"), event.message)
+		message := strings.ReplaceAll(event.message, "\n", "<br>\n") // Multi-line events
+		switch {
+		case event.context:
+			chLogLines <- fmt.Sprintf("<span style=\"color:#999\">%s UTC: %s</span><br>\n",
+				timeT.Format("2006-01-02 15:04:05"), message)
+			return
+		case section == sectionCritical:
+			chLogLines <- fmt.Sprintf("<span style=\"color:#c00\"><b>%s UTC: %s</b></span><br>\n",
+				timeT.Format("2006-01-02 15:04:05"), message)
+		default:
+			chLogLines <- fmt.Sprintf("%s UTC: %s<br>\n", timeT.Format("2006-01-02 15:04:05"), message)
+		}
+		cptLinePrinted++
+	})
+	if err != nil {
+		a.appLog.Error("Failed to read the events of the stream",
+			slog.String("streamName", stream.streamName), slog.String("error", err.Error()))
 	}
-
-	chLogLines <- "<br>\n"
-	return len(stream.events)
+	if headerPrinted {
+		chLogLines <- "<br>\n"
+	}
+	return cptLinePrinted
 }
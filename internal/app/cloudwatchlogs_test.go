@@ -86,7 +86,7 @@ func TestParseAllEventsWithFilter_MultiplePodsSameContainer(t *testing.T) {
 	app := &App{
 		cfg:               configapp.AppConfig{},
 		awscfg:            aws.Config{},
-		rules:             []string{}, // No rules, so all logs will be included
+		rules:             ruleSet{}, // No rules, so all logs will be included
 		lastPeriodToWatch: 3600,
 		appLog:            logger,
 		eventsRateLimit:   rate.NewLimiter(rate.Limit(25), 25),
@@ -224,7 +224,7 @@ func TestParseAllEventsWithFilter_IgnoredContainers(t *testing.T) {
 			ContainerNameToIgnore: []string{"sidecar"},
 		},
 		awscfg:            aws.Config{},
-		rules:             []string{}, // No rules, so all logs will be included
+		rules:             ruleSet{}, // No rules, so all logs will be included
 		lastPeriodToWatch: 3600,
 		appLog:            logger,
 		eventsRateLimit:   rate.NewLimiter(rate.Limit(25), 25),
@@ -294,7 +294,7 @@ func TestParseAllEventsWithFilter_LogsMatchingRules(t *testing.T) {
 	app := &App{
 		cfg:               configapp.AppConfig{},
 		awscfg:            aws.Config{},
-		rules:             []string{"^DEBUG:", "^INFO:"}, // Ignore DEBUG and INFO logs
+		rules:             ruleSet{ignore: mustCompileRules("^DEBUG:", "^INFO:")}, // Ignore DEBUG and INFO logs
 		lastPeriodToWatch: 3600,
 		appLog:            logger,
 		eventsRateLimit:   rate.NewLimiter(rate.Limit(25), 25),
@@ -362,7 +362,7 @@ func TestParseAllEventsWithFilter_MixedContainersInStream(t *testing.T) {
 	app := &App{
 		cfg:               configapp.AppConfig{},
 		awscfg:            aws.Config{},
-		rules:             []string{}, // No rules, so all logs will be included
+		rules:             ruleSet{}, // No rules, so all logs will be included
 		lastPeriodToWatch: 3600,
 		appLog:            logger,
 		eventsRateLimit:   rate.NewLimiter(rate.Limit(25), 25),
@@ -443,4 +443,247 @@ func TestParseAllEventsWithFilter_MixedContainersInStream(t *testing.T) {
 	if !strings.Contains(outputStr, "<b>Container Name</b> :nginx") {
 		t.Error("Stream header should show first container name (nginx)")
 	}
-}
\ No newline at end of file
+}
+
+func TestParseAllEventsWithFilter_LogcheckSections(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+	}))
+
+	app := &App{
+		cfg:    configapp.AppConfig{},
+		awscfg: aws.Config{},
+		rules: ruleSet{
+			ignore:           mustCompileRules("^INFO:", "sudo"),
+			violations:       mustCompileRules("sudo: .*FAILED"),
+			violationsIgnore: mustCompileRules("user=nobody"),
+			cracking:         mustCompileRules("attackalert"),
+		},
+		lastPeriodToWatch: 3600,
+		appLog:            logger,
+		eventsRateLimit:   rate.NewLimiter(rate.Limit(25), 25),
+		logGroupRateLimit: rate.NewLimiter(rate.Limit(10), 10),
+	}
+
+	now := time.Now().Unix() * 1000
+	events := []types.FilteredLogEvent{
+		createLogEvent(now-500000, "stream-1", "pod-1", "app:latest", "app", "INFO: ignored"),
+		createLogEvent(now-400000, "stream-1", "pod-1", "app:latest", "app", "ERROR: system event"),
+		createLogEvent(now-300000, "stream-1", "pod-1", "app:latest", "app", "sudo: auth FAILED user=root"),
+		createLogEvent(now-200000, "stream-1", "pod-1", "app:latest", "app", "sudo: auth FAILED user=nobody"),
+		createLogEvent(now-100000, "stream-2", "pod-2", "app:latest", "app", "INFO: attackalert from 10.0.0.1"),
+	}
+
+	mockClient := &mockCloudWatchClient{
+		events:   events,
+		pageSize: 10,
+	}
+
+	chLogLines := make(chan string, 1000)
+	go func() {
+		_, err := app.parseAllEventsWithFilterClient(context.Background(), mockClient, "test-group", now-3600000, now, chLogLines)
+		if err != nil {
+			t.Errorf("parseAllEventsWithFilterClient returned error: %v", err)
+		}
+		close(chLogLines)
+	}()
+
+	var output []string
+	for line := range chLogLines {
+		output = append(output, line)
+	}
+	outputStr := strings.Join(output, "\n")
+
+	alertsPos := strings.Index(outputStr, "Security Alerts")
+	securityPos := strings.Index(outputStr, "Security Events")
+	systemPos := strings.Index(outputStr, "System Events")
+	if alertsPos == -1 || securityPos == -1 || systemPos == -1 {
+		t.Fatalf("Missing section titles in output:\n%s", outputStr)
+	}
+	if !(alertsPos < securityPos && securityPos < systemPos) {
+		t.Error("Sections are not in logcheck order")
+	}
+
+	attackPos := strings.Index(outputStr, "attackalert from 10.0.0.1")
+	if attackPos < alertsPos || attackPos > securityPos {
+		t.Error("cracking.d match should be in the Security Alerts section even if an ignore rule matches")
+	}
+	violationPos := strings.Index(outputStr, "sudo: auth FAILED user=root")
+	if violationPos < securityPos || violationPos > systemPos {
+		t.Error("violations.d match should be in the Security Events section")
+	}
+	if strings.Contains(outputStr, "user=nobody") {
+		t.Error("Line matching violations.ignore.d and an ignore rule should not be reported")
+	}
+	if strings.Index(outputStr, "ERROR: system event") < systemPos {
+		t.Error("Unmatched line should be in the System Events section")
+	}
+	if strings.Contains(outputStr, "INFO: ignored") {
+		t.Error("Ignored line found in output")
+	}
+}
+
+func TestParseAllEventsWithFilter_IgnoreScope(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+	}))
+
+	now := time.Now().Unix() * 1000
+	events := []types.FilteredLogEvent{
+		createLogEvent(now-300000, "pod-abc-stream", "pod-abc", "nginx:latest", "nginx", "nginx: upstream error"),
+		createLogEvent(now-200000, "pod-abc-stream", "pod-abc", "sidecar:latest", "sidecar", "sidecar: noisy line"),
+		createLogEvent(now-100000, "pod-abc-stream", "pod-abc", "nginx:latest", "nginx", "nginx: second error"),
+	}
+
+	tests := []struct {
+		name        string
+		ignoreScope string
+		expected    []string
+		unexpected  []string
+	}{
+		{
+			name:        "default scope drops the whole stream",
+			ignoreScope: "",
+			unexpected:  []string{"nginx: upstream error", "sidecar: noisy line", "nginx: second error"},
+		},
+		{
+			name:        "event scope only drops the ignored container",
+			ignoreScope: configapp.IgnoreScopeEvent,
+			expected:    []string{"nginx: upstream error", "nginx: second error", "<b>Container Name</b> :nginx"},
+			unexpected:  []string{"sidecar"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &App{
+				cfg: configapp.AppConfig{
+					ContainerNameToIgnore: []string{"sidecar"},
+					IgnoreScope:           tt.ignoreScope,
+				},
+				lastPeriodToWatch: 3600,
+				appLog:            logger,
+				eventsRateLimit:   rate.NewLimiter(rate.Limit(25), 25),
+				logGroupRateLimit: rate.NewLimiter(rate.Limit(10), 10),
+			}
+			mockClient := &mockCloudWatchClient{
+				events:   events,
+				pageSize: 10,
+			}
+
+			chLogLines := make(chan string, 1000)
+			go func() {
+				_, err := app.parseAllEventsWithFilterClient(context.Background(), mockClient, "test-group", now-3600000, now, chLogLines)
+				if err != nil {
+					t.Errorf("parseAllEventsWithFilterClient returned error: %v", err)
+				}
+				close(chLogLines)
+			}()
+
+			var output []string
+			for line := range chLogLines {
+				output = append(output, line)
+			}
+			outputStr := strings.Join(output, "\n")
+
+			for _, line := range tt.expected {
+				if !strings.Contains(outputStr, line) {
+					t.Errorf("Missing %q in output", line)
+				}
+			}
+			for _, line := range tt.unexpected {
+				if strings.Contains(outputStr, line) {
+					t.Errorf("Unexpected %q in output", line)
+				}
+			}
+		})
+	}
+}
+
+func TestParseAllEventsWithFilter_CriticalOfIgnoredContainers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+	}))
+
+	now := time.Now().Unix() * 1000
+	events := []types.FilteredLogEvent{
+		createLogEvent(now-400000, "pod-abc-stream", "pod-abc", "nginx:latest", "nginx", "CRITICAL: disk full"),
+		createLogEvent(now-300000, "pod-abc-stream", "pod-abc", "nginx:latest", "nginx", "nginx: upstream error"),
+		createLogEvent(now-200000, "pod-abc-stream", "pod-abc", "sidecar:latest", "sidecar", "sidecar: noisy line"),
+		createLogEvent(now-150000, "pod-abc-stream", "pod-abc", "sidecar:latest", "sidecar", "CRITICAL: sidecar crashed"),
+		createLogEvent(now-100000, "pod-abc-stream", "pod-abc", "nginx:latest", "nginx", "nginx: second error"),
+	}
+
+	tests := []struct {
+		name        string
+		ignoreScope string
+		printed     int
+		expected    []string
+		unexpected  []string
+	}{
+		{
+			name:       "default scope keeps the critical lines of the stream",
+			printed:    2,
+			expected:   []string{"CRITICAL: disk full", "CRITICAL: sidecar crashed"},
+			unexpected: []string{"nginx: upstream error", "sidecar: noisy line", "nginx: second error"},
+		},
+		{
+			name:        "event scope keeps the critical lines of the ignored container",
+			ignoreScope: configapp.IgnoreScopeEvent,
+			printed:     4,
+			expected:    []string{"CRITICAL: disk full", "CRITICAL: sidecar crashed", "nginx: second error"},
+			unexpected:  []string{"sidecar: noisy line"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &App{
+				cfg: configapp.AppConfig{
+					ContainerNameToIgnore: []string{"sidecar"},
+					IgnoreScope:           tt.ignoreScope,
+				},
+				rules:             ruleSet{alert: mustCompileRules("^CRITICAL:")},
+				lastPeriodToWatch: 3600,
+				appLog:            logger,
+				eventsRateLimit:   rate.NewLimiter(rate.Limit(25), 25),
+				logGroupRateLimit: rate.NewLimiter(rate.Limit(10), 10),
+			}
+			mockClient := &mockCloudWatchClient{
+				events:   events,
+				pageSize: 10,
+			}
+
+			chLogLines := make(chan string, 1000)
+			var printed int
+			go func() {
+				var err error
+				printed, err = app.parseAllEventsWithFilterClient(context.Background(), mockClient, "test-group", now-3600000, now, chLogLines)
+				if err != nil {
+					t.Errorf("parseAllEventsWithFilterClient returned error: %v", err)
+				}
+				close(chLogLines)
+			}()
+
+			var output []string
+			for line := range chLogLines {
+				output = append(output, line)
+			}
+			outputStr := strings.Join(output, "\n")
+
+			if printed != tt.printed {
+				t.Errorf("Expected %d lines printed, got %d", tt.printed, printed)
+			}
+			for _, line := range tt.expected {
+				if !strings.Contains(outputStr, line) {
+					t.Errorf("Missing %q in output", line)
+				}
+			}
+			for _, line := range tt.unexpected {
+				if strings.Contains(outputStr, line) {
+					t.Errorf("Unexpected %q in output", line)
+				}
+			}
+		})
+	}
+}
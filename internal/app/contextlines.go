@@ -0,0 +1,43 @@
+package app
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+// Context lines are ignored lines printed around the reported ones, like grep -C.
+
+// keepContextLine keeps an ignored line of stream as context of the reported lines:
+// after the last reported line if it is within contextlines, else in the ring
+// buffer of the lines preceding the next reported one.
+func (a *App) keepContextLine(lineOfLog fluentDockerLog, stream *streamEvents, event types.FilteredLogEvent) {
+	if a.isEventIgnored(lineOfLog.Kubernetes) {
+		return // Lines of ignored containers are not context
+	}
+	contextEvent := logEvent{
+		timestamp: *event.Timestamp,
+		message:   lineOfLog.Log,
+		context:   true,
+	}
+	if stream.contextAfter > 0 {
+		stream.contextAfter--
+		contextEvent.section = stream.lastSection
+		a.appendEvent(stream, contextEvent)
+		return
+	}
+	if len(stream.contextBefore) == a.cfg.ContextLines {
+		copy(stream.contextBefore, stream.contextBefore[1:])
+		stream.contextBefore = stream.contextBefore[:len(stream.contextBefore)-1]
+	}
+	stream.contextBefore = append(stream.contextBefore, contextEvent)
+}
+
+// addContextBefore adds the buffered context lines before a reported line of section.
+func (a *App) addContextBefore(stream *streamEvents, section reportSection) {
+	for _, contextEvent := range stream.contextBefore {
+		contextEvent.section = section
+		a.appendEvent(stream, contextEvent)
+	}
+	stream.contextBefore = stream.contextBefore[:0]
+	stream.contextAfter = a.cfg.ContextLines
+	stream.lastSection = section
+}
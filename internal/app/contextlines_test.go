@@ -0,0 +1,64 @@
+package app
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/sgaunet/awslogcheck/internal/configapp"
+)
+
+func TestContextLines(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := configapp.AppConfig{ContextLines: 2, ContainerNameToIgnore: []string{"sidecar"}, IgnoreScope: configapp.IgnoreScopeEvent}
+	app := New(context.Background(), cfg, aws.Config{}, 3600, logger)
+	app.rules = ruleSet{ignore: mustCompileRules("^INFO:")}
+
+	now := time.Now().Unix() * 1000
+	lines := []string{"INFO: a", "INFO: b", "INFO: c", "ERROR: x", "INFO: d", "INFO: e", "INFO: f", "INFO: g", "ERROR: y"}
+	events := make([]types.FilteredLogEvent, 0, len(lines)+1)
+	for i, line := range lines {
+		events = append(events, createLogEvent(now-int64(len(lines)-i)*1000, "stream-1", "pod-1", "app:latest", "app", line))
+	}
+	events = append(events, createLogEvent(now-5500, "stream-1", "pod-1", "sidecar:latest", "sidecar", "INFO: sidecar"))
+
+	chLogLines := make(chan string, 1000)
+	var printed int
+	go func() {
+		var err error
+		printed, err = app.parseAllEventsWithFilterClient(context.Background(),
+			&mockCloudWatchClient{events: events, pageSize: 4}, "test-group", now-3600000, now, chLogLines)
+		if err != nil {
+			t.Errorf("parseAllEventsWithFilterClient returned error: %v", err)
+		}
+		close(chLogLines)
+	}()
+	var reported, dimmed []string
+	for line := range chLogLines {
+		if !strings.Contains(line, " UTC: ") {
+			continue
+		}
+		message := line[strings.Index(line, " UTC: ")+len(" UTC: "):]
+		message = message[:strings.IndexAny(message, "<")]
+		if strings.Contains(line, "color:#999") {
+			dimmed = append(dimmed, message)
+		} else {
+			reported = append(reported, message)
+		}
+	}
+
+	if printed != 2 {
+		t.Errorf("Context lines should not be counted, got %d printed lines", printed)
+	}
+	if strings.Join(reported, ",") != "ERROR: x,ERROR: y" {
+		t.Errorf("Unexpected reported lines %v", reported)
+	}
+	if strings.Join(dimmed, ",") != "INFO: b,INFO: c,INFO: d,INFO: e,INFO: f,INFO: g" {
+		t.Errorf("Unexpected context lines %v", dimmed)
+	}
+}
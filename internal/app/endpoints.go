@@ -0,0 +1,42 @@
+package app
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// newCloudWatchLogsClient returns a CloudWatch Logs client of cfg, using the configured endpoint.
+func (a *App) newCloudWatchLogsClient(cfg aws.Config) *cloudwatchlogs.Client {
+	return cloudwatchlogs.NewFromConfig(cfg, func(o *cloudwatchlogs.Options) {
+		o.RetryMaxAttempts = 1 // Retries are handled by withRetry
+		if endpoint := a.cfg.Endpoints.CloudWatchLogs; endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+		}
+	})
+}
+
+// newSTSClient returns a STS client of cfg, using the configured endpoint.
+func (a *App) newSTSClient(cfg aws.Config) *sts.Client {
+	return NewSTSClient(cfg, a.cfg.Endpoints.STS)
+}
+
+// newS3Client returns a S3 client of cfg, using the configured endpoint.
+func (a *App) newS3Client(cfg aws.Config) *s3.Client {
+	return s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if endpoint := a.cfg.Endpoints.S3; endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+			o.UsePathStyle = true // Emulators seldom resolve the bucket subdomains
+		}
+	})
+}
+
+// NewSTSClient returns a STS client of cfg, using endpoint if not empty.
+func NewSTSClient(cfg aws.Config, endpoint string) *sts.Client {
+	return sts.NewFromConfig(cfg, func(o *sts.Options) {
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+		}
+	})
+}
@@ -0,0 +1,44 @@
+package app
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/sgaunet/awslogcheck/internal/configapp"
+)
+
+func TestCloudWatchLogsEndpoint(t *testing.T) {
+	var operations []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		operations = append(operations, r.Header.Get("X-Amz-Target"))
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		_, _ = io.WriteString(w, `{"logGroups":[{"logGroupName":"/emulated","arn":"arn:aws:logs:eu-west-3:000000000000:log-group:/emulated"}]}`)
+	}))
+	defer server.Close()
+
+	cfg := configapp.AppConfig{
+		LogGroup:  "/emulated",
+		Endpoints: configapp.EndpointsConfig{CloudWatchLogs: server.URL},
+	}
+	awscfg := aws.Config{
+		Region:      "eu-west-3",
+		Credentials: credentials.NewStaticCredentialsProvider("test", "test", ""),
+	}
+	app := New(context.Background(), cfg, awscfg, 3600, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	checks, errs := app.resolveTargets(context.Background())
+	if len(errs) > 0 {
+		t.Fatalf("resolveTargets returned errors: %v", errs)
+	}
+	if len(checks) != 1 || checks[0].groupName != "/emulated" {
+		t.Errorf("Expected the log group of the emulator, got %+v", checks)
+	}
+	if len(operations) != 1 || operations[0] != "Logs_20140328.DescribeLogGroups" {
+		t.Errorf("Expected a DescribeLogGroups call to the emulator, got %v", operations)
+	}
+}
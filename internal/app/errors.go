@@ -10,4 +10,15 @@ var (
 	ErrServiceNotConfig  = errors.New("service not configured")
 	ErrSMTPConfigMissing = errors.New("smtp configuration missing")
 	ErrSMTPServerFormat  = errors.New("smtp server format should be: host:port")
-)
\ No newline at end of file
+
+	ErrInvalidSelector         = errors.New("invalid selector")
+	ErrInvalidLogGroupSelector = errors.New("invalid log group selector")
+	ErrInvalidTarget           = errors.New("invalid target")
+	ErrInvalidRules            = errors.New("invalid rules")
+	ErrRuleSourceNotSynced     = errors.New("rule source not synced")
+	ErrRuleStatsNotConfigured  = errors.New("rule statistics file not configured (rulestats.file)")
+	ErrUnknownBackend          = errors.New("unknown backend, expected filter or insights")
+	ErrInsightsQueryFailed     = errors.New("insights query failed")
+	ErrInsightsMissingField    = errors.New("insights query result misses a field")
+	ErrPageNotRecorded         = errors.New("page not recorded")
+)
@@ -0,0 +1,108 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+// slicePagesBuffer is the number of pages a slice fetches ahead of the one being processed.
+const slicePagesBuffer = 64
+
+// timeSlice is a sub-range of the window, fetched by a worker.
+type timeSlice struct {
+	start, end int64 // Milliseconds, both included
+	pages      chan []types.FilteredLogEvent
+	pageCount  int
+	err        error
+}
+
+// splitWindow splits [start, end] in n contiguous sub-ranges.
+func splitWindow(start, end int64, n int) []*timeSlice {
+	if n < 1 {
+		n = 1
+	}
+	if span := end - start + 1; int64(n) > span {
+		n = int(max(span, 1))
+	}
+	slices := make([]*timeSlice, 0, n)
+	for i := range n {
+		slices = append(slices, &timeSlice{
+			start: start + (end-start+1)*int64(i)/int64(n),
+			end:   start + (end-start+1)*int64(i+1)/int64(n) - 1,
+			pages: make(chan []types.FilteredLogEvent, slicePagesBuffer),
+		})
+	}
+	return slices
+}
+
+// fetchSlices splits the window of input in sub-ranges fetched concurrently by a pool of
+// workers, sharing the rate limit of the API. The pages are processed in the order of the
+// window, so the result is the same as a sequential fetch.
+func (a *App) fetchSlices(ctx context.Context, client CloudWatchLogsFilterClient,
+	input *cloudwatchlogs.FilterLogEventsInput, workers int, processPage func([]types.FilteredLogEvent)) (int, error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	nbSlices := a.cfg.Fetch.Slices
+	if nbSlices < workers {
+		nbSlices = workers
+	}
+	slices := splitWindow(*input.StartTime, *input.EndTime, nbSlices)
+	a.appLog.Debug("Fetching time slices", slog.Int("slices", len(slices)), slog.Int("workers", workers))
+
+	// Slices are dispatched in order: a worker blocked on a full buffer only waits for
+	// earlier slices, which are already being fetched.
+	todo := make(chan *timeSlice, len(slices))
+	for _, slice := range slices {
+		todo <- slice
+	}
+	close(todo)
+	var wg sync.WaitGroup
+	for range min(workers, len(slices)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for slice := range todo {
+				a.fetchSlice(ctx, client, input, slice)
+			}
+		}()
+	}
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	pageCount := 0
+	for _, slice := range slices {
+		for events := range slice.pages {
+			processPage(events)
+		}
+		if slice.err != nil {
+			return pageCount, slice.err
+		}
+		pageCount += slice.pageCount
+	}
+	return pageCount, nil
+}
+
+// fetchSlice fetches the pages of a slice, and closes its channel.
+func (a *App) fetchSlice(ctx context.Context, client CloudWatchLogsFilterClient,
+	input *cloudwatchlogs.FilterLogEventsInput, slice *timeSlice) {
+	defer close(slice.pages)
+	sliceInput := *input
+	sliceInput.StartTime = &slice.start
+	sliceInput.EndTime = &slice.end
+	sliceInput.NextToken = nil
+	slice.pageCount, slice.err = a.fetchPages(ctx, client, &sliceInput, func(events []types.FilteredLogEvent) error {
+		select {
+		case slice.pages <- events:
+			return nil
+		case <-ctx.Done():
+			return fmt.Errorf("context cancelled: %w", ctx.Err())
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/sgaunet/awslogcheck/internal/configapp"
+)
+
+var errMockFilter = errors.New("mock filter error")
+
+// mockRangeClient returns the events in the time range of the input, pageSize per page.
+// It can be called concurrently.
+type mockRangeClient struct {
+	events   []types.FilteredLogEvent
+	pageSize int
+	failAt   int64 // Start of the range returning an error, 0 to never fail
+	mu       sync.Mutex
+	calls    int
+}
+
+func (m *mockRangeClient) FilterLogEvents(_ context.Context, params *cloudwatchlogs.FilterLogEventsInput,
+	_ ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.FilterLogEventsOutput, error) {
+	m.mu.Lock()
+	m.calls++
+	m.mu.Unlock()
+	if m.failAt != 0 && aws.ToInt64(params.StartTime) == m.failAt {
+		return nil, errMockFilter
+	}
+	var inRange []types.FilteredLogEvent
+	for _, event := range m.events {
+		ts := aws.ToInt64(event.Timestamp)
+		if ts >= aws.ToInt64(params.StartTime) && ts <= aws.ToInt64(params.EndTime) {
+			inRange = append(inRange, event)
+		}
+	}
+	offset := 0
+	if params.NextToken != nil {
+		offset, _ = strconv.Atoi(*params.NextToken)
+	}
+	end := min(offset+m.pageSize, len(inRange))
+	output := &cloudwatchlogs.FilterLogEventsOutput{Events: inRange[offset:end]}
+	if end < len(inRange) {
+		output.NextToken = aws.String(strconv.Itoa(end))
+	}
+	return output, nil
+}
+
+func TestSplitWindow(t *testing.T) {
+	slices := splitWindow(1000, 1999, 3)
+	if len(slices) != 3 || slices[0].start != 1000 || slices[2].end != 1999 {
+		t.Fatalf("Unexpected slices %+v", slices)
+	}
+	for i := 1; i < len(slices); i++ {
+		if slices[i].start != slices[i-1].end+1 {
+			t.Errorf("Slices %d and %d are not contiguous", i-1, i)
+		}
+	}
+	if len(splitWindow(0, 1, 5)) != 2 {
+		t.Error("A slice should not be smaller than a millisecond")
+	}
+}
+
+func TestFetchSlicesMatchesSequentialFetch(t *testing.T) {
+	now := time.Now().Unix() * 1000
+	var events []types.FilteredLogEvent
+	for i := range 200 {
+		msg := fmt.Sprintf("ERROR: event %d", i)
+		if i%3 == 0 {
+			msg = fmt.Sprintf("INFO: event %d", i)
+		}
+		stream := fmt.Sprintf("stream-%d", i%4)
+		events = append(events, createLogEvent(now-3600000+int64(i)*17000, stream, "pod", "app:latest", "app", msg))
+	}
+
+	run := func(fetch configapp.FetchConfig) []string {
+		logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+		app := New(context.Background(), configapp.AppConfig{Fetch: fetch, ContextLines: 1}, aws.Config{}, 3600, logger)
+		app.rules = ruleSet{ignore: mustCompileRules("^INFO:")}
+		chLogLines := make(chan string, 10000)
+		_, err := app.parseAllEventsWithFilterClient(context.Background(), &mockRangeClient{events: events, pageSize: 7},
+			"test-group", now-3600000, now, chLogLines)
+		if err != nil {
+			t.Fatalf("parseAllEventsWithFilterClient returned error: %v", err)
+		}
+		close(chLogLines)
+		var lines []string
+		for line := range chLogLines {
+			lines = append(lines, line)
+		}
+		return lines
+	}
+
+	sequential := run(configapp.FetchConfig{})
+	parallel := run(configapp.FetchConfig{Workers: 4, Slices: 9})
+	if len(sequential) == 0 || strings.Join(sequential, "") != strings.Join(parallel, "") {
+		t.Errorf("Parallel fetch differs from the sequential one:\n%s\n---\n%s",
+			strings.Join(sequential, ""), strings.Join(parallel, ""))
+	}
+}
+
+func TestFetchSlicesError(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	app := New(context.Background(), configapp.AppConfig{Fetch: configapp.FetchConfig{Workers: 2, Slices: 4}},
+		aws.Config{}, 3600, logger)
+	now := time.Now().Unix() * 1000
+	var events []types.FilteredLogEvent
+	for i := range 100 {
+		events = append(events, createLogEvent(now-3600000+int64(i)*36000, "stream", "pod", "app:latest", "app", "ERROR"))
+	}
+	slices := splitWindow(now-3600000, now, 4)
+	client := &mockRangeClient{events: events, pageSize: 1, failAt: slices[1].start}
+
+	_, err := app.parseAllEventsWithFilterClient(context.Background(), client, "test-group", now-3600000, now,
+		make(chan string, 1000))
+	if !errors.Is(err, errMockFilter) {
+		t.Errorf("Expected the error of the slice, got %v", err)
+	}
+}
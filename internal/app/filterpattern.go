@@ -0,0 +1,157 @@
+package app
+
+import (
+	"log/slog"
+	"regexp/syntax"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// filterPatternAuto derives the filter pattern from the alert rules, and the violations and
+// cracking rules of the logcheck layout.
+const filterPatternAuto = "auto"
+
+// filterPattern returns the CloudWatch filter pattern of groupName, empty to get every event.
+// The terms of the alert rules are added to a pattern made of optional terms (?ERROR ?panic),
+// so that alert rules still see the lines they match. No pattern is used with multi-line
+// events or context lines, which need the lines filtered out.
+func (a *App) filterPattern(groupName string) string {
+	pattern := strings.TrimSpace(a.cfg.GetFilterPattern(groupName))
+	if pattern == "" {
+		return ""
+	}
+	if a.multiline != nil || a.cfg.ContextLines > 0 {
+		a.appLog.Warn("The filter pattern is not used with multiline or contextlines, every event is fetched",
+			slog.String("groupName", groupName))
+		return ""
+	}
+	alertTerms, derivable := a.alertTerms()
+	hasAlertRules := len(a.rules.alert)+len(a.rules.violations)+len(a.rules.cracking) > 0
+	if pattern == filterPatternAuto {
+		if !derivable || len(alertTerms) == 0 {
+			a.appLog.Warn("Cannot derive a filter pattern from the alert rules, every event is fetched",
+				slog.String("groupName", groupName))
+			return ""
+		}
+		return formatOptionalTerms(alertTerms)
+	}
+	terms, ok := parseOptionalTerms(pattern)
+	if !ok || !hasAlertRules {
+		if hasAlertRules {
+			a.appLog.Warn("Alert rules may miss lines filtered out by the filter pattern",
+				slog.String("groupName", groupName), slog.String("filterPattern", pattern))
+		}
+		return pattern
+	}
+	if !derivable {
+		a.appLog.Warn("Alert rules may miss lines filtered out by the filter pattern",
+			slog.String("groupName", groupName), slog.String("filterPattern", pattern))
+		return pattern
+	}
+	seen := make(map[string]bool, len(terms))
+	for _, term := range terms {
+		seen[term] = true
+	}
+	for _, term := range alertTerms {
+		if !seen[term] {
+			terms = append(terms, term)
+			seen[term] = true
+		}
+	}
+	return formatOptionalTerms(terms)
+}
+
+// alertTerms returns the literal texts matched by the rules whose lines are always
+// reported: alert rules, and violations and cracking rules of the logcheck layout.
+// It returns false if one of them is not a case-sensitive literal, and so can't be
+// expressed as a filter pattern term.
+func (a *App) alertTerms() ([]string, bool) {
+	reported := slices.Concat(a.rules.alert, a.rules.violations, a.rules.cracking)
+	terms := make([]string, 0, len(reported))
+	for _, r := range reported {
+		if r.source.IsField() {
+			return nil, false
+		}
+		term, ok := literalOfPattern(r.source.Pattern)
+		if !ok {
+			return nil, false
+		}
+		terms = append(terms, term)
+	}
+	return terms, true
+}
+
+// literalOfPattern returns the text matched by a regexp made of a literal,
+// optionally anchored. Anchors are dropped: the term matches a superset of the lines.
+func literalOfPattern(pattern string) (string, bool) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return "", false
+	}
+	re = re.Simplify()
+	nodes := []*syntax.Regexp{re}
+	if re.Op == syntax.OpConcat {
+		nodes = re.Sub
+	}
+	var literal strings.Builder
+	for _, node := range nodes {
+		switch node.Op {
+		case syntax.OpBeginLine, syntax.OpBeginText, syntax.OpEndLine, syntax.OpEndText:
+		case syntax.OpLiteral:
+			if node.Flags&syntax.FoldCase != 0 {
+				return "", false
+			}
+			literal.WriteString(string(node.Rune))
+		default:
+			return "", false
+		}
+	}
+	// Quotes and backslashes are escaped in the JSON of the events
+	text := literal.String()
+	if text == "" || strings.ContainsAny(text, "\"\\") {
+		return "", false
+	}
+	return text, true
+}
+
+// parseOptionalTerms splits a filter pattern made only of optional terms: ?ERROR ?"out of memory".
+func parseOptionalTerms(pattern string) ([]string, bool) {
+	var terms []string
+	rest := strings.TrimSpace(pattern)
+	for rest != "" {
+		if !strings.HasPrefix(rest, "?") {
+			return nil, false
+		}
+		rest = rest[1:]
+		var term string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end == -1 {
+				return nil, false
+			}
+			term, rest = rest[1:end+1], rest[end+2:]
+		} else {
+			end := strings.IndexAny(rest, " \t")
+			if end == -1 {
+				end = len(rest)
+			}
+			term, rest = rest[:end], rest[end:]
+		}
+		if term == "" {
+			return nil, false
+		}
+		terms = append(terms, term)
+		rest = strings.TrimSpace(rest)
+	}
+	return terms, len(terms) > 0
+}
+
+// formatOptionalTerms builds a filter pattern matching any of terms.
+func formatOptionalTerms(terms []string) string {
+	parts := make([]string, 0, len(terms))
+	for _, term := range terms {
+		parts = append(parts, "?"+strconv.Quote(term))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,102 @@
+package app
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/sgaunet/awslogcheck/internal/configapp"
+)
+
+func TestBuildFilterLogEventsInputFilterPattern(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	tests := []struct {
+		name     string
+		cfg      configapp.AppConfig
+		alert    []string
+		expected string
+	}{
+		{"no pattern", configapp.AppConfig{}, []string{"OOMKilled"}, ""},
+		{"pattern without alert rules", configapp.AppConfig{FilterPattern: "?ERROR ?panic"}, nil, "?ERROR ?panic"},
+		{
+			"alert terms appended", configapp.AppConfig{FilterPattern: `?ERROR ?"out of memory"`},
+			[]string{"^OOMKilled$", "ERROR"}, `?"ERROR" ?"out of memory" ?"OOMKilled"`,
+		},
+		{
+			"other pattern kept", configapp.AppConfig{FilterPattern: `{ $.level = "error" }`},
+			[]string{"OOMKilled"}, `{ $.level = "error" }`,
+		},
+		{
+			"alert rule not literal", configapp.AppConfig{FilterPattern: "?ERROR"},
+			[]string{"OOM(Killed)?"}, "?ERROR",
+		},
+		{"auto", configapp.AppConfig{FilterPattern: "auto"}, []string{"OOMKilled", `^panic: `}, `?"OOMKilled" ?"panic: "`},
+		{"auto case-insensitive", configapp.AppConfig{FilterPattern: "auto"}, []string{"(?i)error"}, ""},
+		{"auto without alert rules", configapp.AppConfig{FilterPattern: "auto"}, nil, ""},
+		{
+			"per group", configapp.AppConfig{FilterPattern: "?ERROR", FilterPatterns: map[string]string{"test-group": ""}},
+			nil, "",
+		},
+		{
+			"other group", configapp.AppConfig{FilterPatterns: map[string]string{"other-group": "?ERROR"}},
+			nil, "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := New(context.Background(), tt.cfg, aws.Config{}, 3600, logger)
+			app.rules = ruleSet{alert: mustCompileRules(tt.alert...)}
+			input := app.buildFilterLogEventsInput("test-group", 0, 3600000)
+			got := aws.ToString(input.FilterPattern)
+			if got != tt.expected {
+				t.Errorf("Expected filter pattern %q, got %q", tt.expected, got)
+			}
+			if tt.expected == "" && input.FilterPattern != nil {
+				t.Error("FilterPattern should not be set")
+			}
+		})
+	}
+}
+
+// TestFilterPatternLogcheckAndContext tests the logcheck rules always reported, and the
+// settings needing the lines filtered out
+func TestFilterPatternLogcheckAndContext(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	tests := []struct {
+		name     string
+		cfg      configapp.AppConfig
+		rules    ruleSet
+		expected string
+	}{
+		{
+			"auto with violations", configapp.AppConfig{FilterPattern: "auto"},
+			ruleSet{violations: mustCompileRules("segfault"), cracking: mustCompileRules("^Invalid user")},
+			`?"segfault" ?"Invalid user"`,
+		},
+		{
+			"auto with regexp violations", configapp.AppConfig{FilterPattern: "auto"},
+			ruleSet{alert: mustCompileRules("OOMKilled"), violations: mustCompileRules("fail(ed|ure)")}, "",
+		},
+		{
+			"cracking terms appended", configapp.AppConfig{FilterPattern: "?ERROR"},
+			ruleSet{cracking: mustCompileRules("Invalid user")}, `?"ERROR" ?"Invalid user"`,
+		},
+		{
+			"multiline", configapp.AppConfig{FilterPattern: "?ERROR",
+				Multiline: configapp.MultilineConfig{Detectors: []string{"java"}}},
+			ruleSet{}, "",
+		},
+		{"context lines", configapp.AppConfig{FilterPattern: "?ERROR", ContextLines: 2}, ruleSet{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := New(context.Background(), tt.cfg, aws.Config{}, 3600, logger)
+			app.rules = tt.rules
+			if got := app.filterPattern("test-group"); got != tt.expected {
+				t.Errorf("Expected filter pattern %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -2,11 +2,14 @@ package app
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"regexp"
 	"testing"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/sgaunet/awslogcheck/internal/configapp"
+	"github.com/sgaunet/awslogcheck/internal/rules"
 	"io"
 	"log/slog"
 )
@@ -49,12 +52,6 @@ func TestIsLineMatchWithOneRule(t *testing.T) {
 			rules:       []string{},
 			expectMatch: false,
 		},
-		{
-			name:        "Invalid regex",
-			line:        "Test log",
-			rules:       []string{"[invalid(regex"},
-			expectMatch: false, // Should not panic, just not match
-		},
 	}
 
 	// Create a test app instance
@@ -65,7 +62,7 @@ func TestIsLineMatchWithOneRule(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := app.isLineMatchWithOneRule(tt.line, tt.rules)
+			result := app.isLineMatchWithOneRule(&logMessage{line: tt.line}, mustCompileRules(tt.rules...))
 			if result != tt.expectMatch {
 				t.Errorf("Expected %v but got %v", tt.expectMatch, result)
 			}
@@ -73,6 +70,98 @@ func TestIsLineMatchWithOneRule(t *testing.T) {
 	}
 }
 
+// mustCompileRules compiles patterns into rules, for tests
+func mustCompileRules(patterns ...string) []*rule {
+	compiled := make([]*rule, 0, len(patterns))
+	for _, pattern := range patterns {
+		compiled = append(compiled, &rule{
+			re:     regexp.MustCompile(pattern),
+			source: rules.Rule{Pattern: pattern, Raw: pattern},
+		})
+	}
+	return compiled
+}
+
+// TestCompileRulesSkipsInvalid tests that incorrect rules are skipped
+func TestCompileRulesSkipsInvalid(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	app := New(context.Background(), configapp.AppConfig{}, aws.Config{}, 3600, logger)
+
+	compiled := app.compileRules([]rules.Rule{
+		{Pattern: "^DEBUG:"},
+		{Pattern: "[invalid(regex", File: "app.rule", Line: 2},
+		{Pattern: "^INFO:"},
+	})
+	if len(compiled) != 2 {
+		t.Fatalf("Expected 2 compiled rules, got %d", len(compiled))
+	}
+	if compiled[1].source.Pattern != "^INFO:" {
+		t.Errorf("Unexpected rule %q", compiled[1].source.Pattern)
+	}
+}
+
+// TestClassifyLine tests that alert rules override ignore rules
+func TestClassifyLine(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	app := New(context.Background(), configapp.AppConfig{}, aws.Config{}, 3600, logger)
+	app.rules = ruleSet{
+		ignore: mustCompileRules("^INFO:", "Reason: OOMKilled"),
+		alert:  mustCompileRules("OOMKilled", "^panic:", "AccessDenied"),
+	}
+
+	tests := []struct {
+		line     string
+		expected reportSection
+	}{
+		{line: "INFO: request served", expected: sectionIgnored},
+		{line: "ERROR: request failed", expected: sectionSystem},
+		{line: "panic: runtime error", expected: sectionCritical},
+		{line: "INFO: AccessDenied for user", expected: sectionCritical},
+		{line: "Reason: OOMKilled", expected: sectionCritical},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			if got := app.classifyLine(tt.line); got != tt.expected {
+				t.Errorf("Expected section %v but got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestClassifyLineJSONFields tests field rules on JSON messages, and the regexp fallback
+func TestClassifyLineJSONFields(t *testing.T) {
+	rulesDir := t.TempDir()
+	content := "^INFO:\n#!fields\nlevel in [debug, info]\nstatus < 500 && msg =~ ^request\n#!alert\nlevel == fatal\n"
+	if err := os.WriteFile(filepath.Join(rulesDir, "json.rule"), []byte(content), 0o644); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	app := New(context.Background(), configapp.AppConfig{RulesDir: rulesDir}, aws.Config{}, 3600, logger)
+	if err := app.LoadRules(); err != nil {
+		t.Fatalf("LoadRules returned error: %v", err)
+	}
+
+	tests := []struct {
+		line     string
+		expected reportSection
+	}{
+		{line: `{"level":"info","msg":"started"}`, expected: sectionIgnored},
+		{line: `{"level":"warn","status":404,"msg":"request failed"}`, expected: sectionIgnored},
+		{line: `{"level":"error","status":502,"msg":"request failed"}`, expected: sectionSystem},
+		{line: `{"level":"fatal","msg":"cannot start"}`, expected: sectionCritical},
+		{line: "INFO: not JSON", expected: sectionIgnored},
+		{line: "level=info not JSON", expected: sectionSystem},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			if got := app.classifyLine(tt.line); got != tt.expected {
+				t.Errorf("Expected section %v but got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
 // BenchmarkIsLineMatchWithOneRule benchmarks rule matching
 func BenchmarkIsLineMatchWithOneRule(b *testing.B) {
 	line := "2024-01-01 10:00:00 ERROR: Database connection failed with timeout after 30 seconds"
@@ -91,10 +180,11 @@ func BenchmarkIsLineMatchWithOneRule(b *testing.B) {
 		Level: slog.LevelError,
 	}))
 	app := New(context.Background(), configapp.AppConfig{}, aws.Config{}, 3600, logger)
+	compiled := mustCompileRules(rules...)
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_ = app.isLineMatchWithOneRule(line, rules)
+		_ = app.isLineMatchWithOneRule(&logMessage{line: line}, compiled)
 	}
 }
 
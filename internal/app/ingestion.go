@@ -0,0 +1,198 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+const (
+	defaultIngestionMaxWait      = 600 // Seconds, maximum wait before checking
+	defaultIngestionPollInterval = 15  // Seconds between two checks of the log streams
+	defaultIngestionQuiet        = 120 // Seconds without ingestion after which a stream is idle
+
+	// lastEventTimestamp is eventually consistent, typically updated within an hour: the streams
+	// are listed until their last event is older than the window by more than that. A value
+	// after the window is reliable, an older one is checked with FilterLogEvents.
+	lastEventTimestampSlackMs = 3600 * millisecondsMultiplier
+)
+
+// CloudWatchLogsStreamsClient interface for testing.
+type CloudWatchLogsStreamsClient interface {
+	CloudWatchLogsFilterClient
+	DescribeLogStreams(ctx context.Context,
+		params *cloudwatchlogs.DescribeLogStreamsInput,
+		optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogStreamsOutput, error)
+}
+
+// ingestionDelays returns the maximum wait and the poll interval of the ingestion of the window,
+// no wait if the maximum is negative.
+func (a *App) ingestionDelays() (time.Duration, time.Duration) {
+	maxWait := a.cfg.Ingestion.MaxWait
+	if maxWait == 0 {
+		maxWait = defaultIngestionMaxWait
+	}
+	pollInterval := a.cfg.Ingestion.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = defaultIngestionPollInterval
+	}
+	return time.Duration(max(maxWait, 0)) * time.Second, time.Duration(pollInterval) * time.Second
+}
+
+// waitForIngestion waits until the streams of the log groups active in the window from
+// minTimeStampInMs to maxTimeStampInMs have an event past its end or are idle, and returns
+// the log groups still waited for when the maximum wait is reached.
+func (a *App) waitForIngestion(ctx context.Context, checks []logGroupCheck,
+	minTimeStampInMs, maxTimeStampInMs int64) []string {
+	maxWait, pollInterval := a.ingestionDelays()
+	if maxWait == 0 {
+		return nil
+	}
+	return a.waitUntilIngested(ctx, maxWait, pollInterval, func(ctx context.Context) []string {
+		var pending []string
+		now := time.Now().UnixMilli()
+		for _, check := range checks {
+			streams, err := a.pendingStreams(ctx, check.client, check.groupName, minTimeStampInMs, maxTimeStampInMs, now)
+			if err != nil {
+				// The check itself reports the errors of the log group
+				a.appLog.Warn("Cannot check the ingestion of the log group", slog.String("target", check.target),
+					slog.String("groupName", check.groupName), slog.String("error", err.Error()))
+				continue
+			}
+			if len(streams) > 0 {
+				a.appLog.Debug("Log streams not ingested yet", slog.String("target", check.target),
+					slog.String("groupName", check.groupName), slog.Any("streams", streams))
+				pending = append(pending, check.title())
+			}
+		}
+		return pending
+	})
+}
+
+// waitUntilIngested calls pending every pollInterval until it returns nothing, or maxWait is
+// reached, and returns its last result.
+func (a *App) waitUntilIngested(ctx context.Context, maxWait, pollInterval time.Duration,
+	pending func(context.Context) []string) []string {
+	deadline := time.Now().Add(maxWait)
+	for {
+		groups := pending(ctx)
+		if len(groups) == 0 {
+			return nil
+		}
+		wait := min(pollInterval, time.Until(deadline))
+		if wait <= 0 {
+			a.appLog.Warn("Gave up waiting for the ingestion of the window", slog.Any("logGroups", groups))
+			return groups
+		}
+		a.appLog.Info("Waiting for the ingestion of the window", slog.Any("logGroups", groups),
+			slog.Duration("wait", wait))
+		select {
+		case <-ctx.Done():
+			return groups
+		case <-time.After(wait):
+		}
+	}
+}
+
+// pendingStreams returns the streams of a log group whose events of the window may not be
+// ingested yet: active in the window and ingesting recently, but without an event after the
+// end of the window.
+func (a *App) pendingStreams(ctx context.Context, client CloudWatchLogsStreamsClient, groupName string,
+	minTimeStampInMs, maxTimeStampInMs, now int64) ([]string, error) {
+	quiet := a.cfg.Ingestion.Quiet
+	if quiet <= 0 {
+		quiet = defaultIngestionQuiet
+	}
+	quietMs := int64(quiet) * millisecondsMultiplier
+
+	var pending []string
+	params := cloudwatchlogs.DescribeLogStreamsInput{
+		LogGroupName: aws.String(groupName),
+		OrderBy:      types.OrderByLastEventTime,
+		Descending:   aws.Bool(true),
+	}
+	for {
+		var res *cloudwatchlogs.DescribeLogStreamsOutput
+		err := a.withRetry(ctx, "DescribeLogStreams", a.logGroupRateLimit, maxLogGroupAPICallPerSecond, func() error {
+			var err error
+			res, err = client.DescribeLogStreams(ctx, &params)
+			return err //nolint:wrapcheck // wrapped below, once retries are exhausted
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe log streams: %w", err)
+		}
+		for _, stream := range res.LogStreams {
+			lastEvent := aws.ToInt64(stream.LastEventTimestamp)
+			if lastEvent != 0 && lastEvent < minTimeStampInMs-lastEventTimestampSlackMs {
+				return pending, nil // Sorted by last event: the next ones are older
+			}
+			lastIngestion := aws.ToInt64(stream.LastIngestionTime)
+			switch {
+			case lastEvent > maxTimeStampInMs:
+				continue // The agent is past the window
+			case lastIngestion < minTimeStampInMs:
+				continue // Not active in the window
+			case now-lastIngestion >= quietMs:
+				continue // Idle, or stopped
+			}
+			// lastEventTimestamp may be late: an event after the window tells that it's ingested
+			streamName := aws.ToString(stream.LogStreamName)
+			complete, err := a.hasEventAfter(ctx, client, groupName, streamName, maxTimeStampInMs)
+			if err != nil {
+				return nil, err
+			}
+			if !complete {
+				pending = append(pending, streamName)
+			}
+		}
+		if res.NextToken == nil {
+			return pending, nil
+		}
+		params.NextToken = res.NextToken
+	}
+}
+
+// hasEventAfter reports whether a stream has an event after maxTimeStampInMs. The agents send
+// the events of a stream in order, so the ones before are ingested.
+func (a *App) hasEventAfter(ctx context.Context, client CloudWatchLogsFilterClient, groupName, streamName string,
+	maxTimeStampInMs int64) (bool, error) {
+	params := cloudwatchlogs.FilterLogEventsInput{
+		LogGroupName:   aws.String(groupName),
+		LogStreamNames: []string{streamName},
+		StartTime:      aws.Int64(maxTimeStampInMs + 1),
+		Limit:          aws.Int32(1),
+	}
+	for {
+		var res *cloudwatchlogs.FilterLogEventsOutput
+		err := a.withRetry(ctx, "FilterLogEvents", a.eventsRateLimit, maxEventsAPICallPerSecond, func() error {
+			var err error
+			res, err = client.FilterLogEvents(ctx, &params)
+			return err //nolint:wrapcheck // wrapped below, once retries are exhausted
+		})
+		if err != nil {
+			return false, fmt.Errorf("failed to filter log events of %s: %w", streamName, err)
+		}
+		if len(res.Events) > 0 {
+			return true, nil
+		}
+		// Pages may be empty before the end of the search
+		if res.NextToken == nil {
+			return false, nil
+		}
+		params.NextToken = res.NextToken
+	}
+}
+
+// incompleteNotice returns the notice of the report when the window of groups may not be
+// completely ingested.
+func incompleteNotice(groups []string) string {
+	return "<p><b>Possibly incomplete</b>: events of the end of the window may not be ingested yet for " +
+		html.EscapeString(strings.Join(groups, ", ")) + "</p>\n"
+}
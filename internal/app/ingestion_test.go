@@ -0,0 +1,161 @@
+package app
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/sgaunet/awslogcheck/internal/configapp"
+)
+
+// mockStreamsClient returns its streams by pages of two, and the timestamps of the events of
+// the streams after an empty page.
+type mockStreamsClient struct {
+	streams     []types.LogStream
+	events      map[string][]int64
+	calls       int
+	filterCalls int
+}
+
+func (m *mockStreamsClient) FilterLogEvents(_ context.Context, params *cloudwatchlogs.FilterLogEventsInput,
+	_ ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.FilterLogEventsOutput, error) {
+	m.filterCalls++
+	if params.NextToken == nil {
+		return &cloudwatchlogs.FilterLogEventsOutput{NextToken: aws.String("next")}, nil
+	}
+	output := &cloudwatchlogs.FilterLogEventsOutput{}
+	for _, timestamp := range m.events[params.LogStreamNames[0]] {
+		if timestamp >= aws.ToInt64(params.StartTime) {
+			output.Events = append(output.Events, types.FilteredLogEvent{Timestamp: aws.Int64(timestamp)})
+			break
+		}
+	}
+	return output, nil
+}
+
+func (m *mockStreamsClient) DescribeLogStreams(_ context.Context, params *cloudwatchlogs.DescribeLogStreamsInput,
+	_ ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogStreamsOutput, error) {
+	m.calls++
+	start := 0
+	if params.NextToken != nil {
+		start, _ = strconv.Atoi(*params.NextToken)
+	}
+	end := min(start+2, len(m.streams))
+	output := &cloudwatchlogs.DescribeLogStreamsOutput{LogStreams: m.streams[start:end]}
+	if end < len(m.streams) {
+		output.NextToken = aws.String(strconv.Itoa(end))
+	}
+	return output, nil
+}
+
+// logStream returns a stream with its last event and ingestion times.
+func logStream(name string, lastEvent, lastIngestion int64) types.LogStream {
+	return types.LogStream{
+		LogStreamName:      aws.String(name),
+		LastEventTimestamp: aws.Int64(lastEvent),
+		LastIngestionTime:  aws.Int64(lastIngestion),
+	}
+}
+
+func TestPendingStreams(t *testing.T) {
+	const (
+		start = int64(1760770800000) // 07:00
+		end   = start + 3600000 - 1  // 07:59:59.999
+		now   = end + 30000          // 08:00:30
+	)
+	client := &mockStreamsClient{
+		streams: []types.LogStream{
+			logStream("event-after-end", end+5000, end+6000),
+			logStream("flushing", end-1000, end+2000),
+			logStream("idle", end-400000, end-399000),
+			// lastEventTimestamp is late on busy streams
+			logStream("busy", end-600000, now-1000),
+			logStream("lagging", end-600000, now-5000),
+			logStream("before-window", start-100000, start-99000),
+			logStream("old", start-2*3600000, start-2*3600000),
+			logStream("never-listed", end, now),
+		},
+		events: map[string][]int64{
+			"flushing": {end - 1000},
+			"busy":     {end - 1000, end + 1000, now - 1000},
+			"lagging":  {end - 600000},
+		},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	app := New(context.Background(), configapp.AppConfig{}, aws.Config{}, 3600, logger)
+
+	pending, err := app.pendingStreams(context.Background(), client, "app", start, end, now)
+	if err != nil {
+		t.Fatalf("pendingStreams returned error: %v", err)
+	}
+	if !slices.Equal(pending, []string{"flushing", "lagging"}) {
+		t.Errorf("Expected the flushing and lagging streams, got %v", pending)
+	}
+	if client.calls != 4 {
+		t.Errorf("Expected the listing to stop at the old stream after 4 pages, got %d", client.calls)
+	}
+	if client.filterCalls != 6 {
+		t.Errorf("Expected 2 pages of FilterLogEvents for the flushing, busy and lagging streams, got %d",
+			client.filterCalls)
+	}
+}
+
+func TestWaitUntilIngested(t *testing.T) {
+	tests := []struct {
+		name     string
+		complete int // Call of pending returning nothing, 0 for never
+		expected []string
+	}{
+		{name: "complete", complete: 1},
+		{name: "complete after polls", complete: 3},
+		{name: "gave up", expected: []string{"prod: app"}},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := New(context.Background(), configapp.AppConfig{}, aws.Config{}, 3600, logger)
+			calls := 0
+			groups := app.waitUntilIngested(context.Background(), 200*time.Millisecond, 20*time.Millisecond,
+				func(context.Context) []string {
+					calls++
+					if calls == tt.complete {
+						return nil
+					}
+					return []string{"prod: app"}
+				})
+			if !slices.Equal(groups, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, groups)
+			}
+			if tt.complete > 0 && calls != tt.complete {
+				t.Errorf("Expected %d calls, got %d", tt.complete, calls)
+			}
+		})
+	}
+}
+
+func TestIngestionDelays(t *testing.T) {
+	tests := []struct {
+		cfg          configapp.IngestionConfig
+		maxWait      time.Duration
+		pollInterval time.Duration
+	}{
+		{cfg: configapp.IngestionConfig{}, maxWait: 600 * time.Second, pollInterval: 15 * time.Second},
+		{cfg: configapp.IngestionConfig{MaxWait: 60, PollInterval: 5}, maxWait: 60 * time.Second, pollInterval: 5 * time.Second},
+		{cfg: configapp.IngestionConfig{MaxWait: -1}, maxWait: 0, pollInterval: 15 * time.Second},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, tt := range tests {
+		app := New(context.Background(), configapp.AppConfig{Ingestion: tt.cfg}, aws.Config{}, 3600, logger)
+		maxWait, pollInterval := app.ingestionDelays()
+		if maxWait != tt.maxWait || pollInterval != tt.pollInterval {
+			t.Errorf("%+v: expected %v and %v, got %v and %v", tt.cfg, tt.maxWait, tt.pollInterval, maxWait, pollInterval)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"text/template"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+// Defaults of the Logs Insights backend.
+const (
+	defaultInsightsQuery        = "fields @timestamp, @message, @logStream | sort @timestamp asc"
+	defaultInsightsLimit        = 10000 // Maximum allowed by Logs Insights
+	defaultInsightsPollInterval = 1000  // Milliseconds
+	insightsTimestampLayout     = "2006-01-02 15:04:05.000"
+)
+
+// CloudWatchLogsInsightsClient interface for testing.
+type CloudWatchLogsInsightsClient interface {
+	StartQuery(ctx context.Context,
+		params *cloudwatchlogs.StartQueryInput,
+		optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error)
+	GetQueryResults(ctx context.Context,
+		params *cloudwatchlogs.GetQueryResultsInput,
+		optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error)
+}
+
+// insightsQueryData is given to the query template.
+type insightsQueryData struct {
+	LogGroup  string
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+// parseAllEventsWithInsightsClient fetches the events of the window with Logs Insights queries.
+// A window returning the maximum number of results is split in two, until every event is fetched.
+func (a *App) parseAllEventsWithInsightsClient(ctx context.Context, client CloudWatchLogsInsightsClient,
+	groupName string, minTimeStamp int64, maxTimeStamp int64, chLogLines chan<- string) (int, error) {
+	tmpl, err := a.insightsQueryTemplate()
+	if err != nil {
+		return 0, err
+	}
+	streamGroups := make(map[string]*streamEvents)
+	defer a.releaseStreams(streamGroups)
+	eventCount := 0
+	err = a.queryInsightsWindow(ctx, client, tmpl, groupName,
+		minTimeStamp/millisecondsMultiplier, maxTimeStamp/millisecondsMultiplier, streamGroups, &eventCount)
+	if err != nil {
+		return 0, err
+	}
+	if a.multiline != nil {
+		a.flushMultilineEvents(streamGroups)
+	}
+	a.appLog.Debug("Completed Logs Insights processing",
+		slog.Int("totalEvents", eventCount),
+		slog.Int("streams", len(streamGroups)))
+	return a.outputStreamEvents(streamGroups, chLogLines, eventCount)
+}
+
+func (a *App) insightsQueryTemplate() (*template.Template, error) {
+	query := a.cfg.Insights.Query
+	if query == "" {
+		query = defaultInsightsQuery
+	}
+	tmpl, err := template.New("query").Parse(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse insights query: %w", err)
+	}
+	return tmpl, nil
+}
+
+// queryInsightsWindow processes the events between start and end, in seconds, both included.
+func (a *App) queryInsightsWindow(ctx context.Context, client CloudWatchLogsInsightsClient, tmpl *template.Template,
+	groupName string, start, end int64, streamGroups map[string]*streamEvents, eventCount *int) error {
+	limit := a.cfg.Insights.Limit
+	if limit <= 0 {
+		limit = defaultInsightsLimit
+	}
+	results, err := a.runInsightsQuery(ctx, client, tmpl, groupName, start, end, limit)
+	if err != nil {
+		return err
+	}
+	if len(results) >= int(limit) {
+		if start < end {
+			a.appLog.Debug("Insights query limit reached, splitting the window",
+				slog.Int64("start", start), slog.Int64("end", end))
+			middle := start + (end-start)/2
+			if err := a.queryInsightsWindow(ctx, client, tmpl, groupName, start, middle, streamGroups, eventCount); err != nil {
+				return err
+			}
+			return a.queryInsightsWindow(ctx, client, tmpl, groupName, middle+1, end, streamGroups, eventCount)
+		}
+		a.appLog.Warn("Insights query limit reached in one second, events are missing",
+			slog.String("groupName", groupName), slog.Int64("second", start))
+	}
+
+	events := make([]types.FilteredLogEvent, 0, len(results))
+	for _, row := range results {
+		event, err := insightsEvent(row)
+		if err != nil {
+			return err
+		}
+		events = append(events, event)
+	}
+	a.processEventsInPage(events, streamGroups, eventCount)
+	return nil
+}
+
+// runInsightsQuery starts a query and waits for its results.
+func (a *App) runInsightsQuery(ctx context.Context, client CloudWatchLogsInsightsClient, tmpl *template.Template,
+	groupName string, start, end int64, limit int32) ([][]types.ResultField, error) {
+	var query bytes.Buffer
+	err := tmpl.Execute(&query, insightsQueryData{
+		LogGroup:  groupName,
+		StartTime: time.Unix(start, 0).UTC(),
+		EndTime:   time.Unix(end, 0).UTC(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute insights query template: %w", err)
+	}
+	var started *cloudwatchlogs.StartQueryOutput
+	err = a.withRetry(ctx, "StartQuery", a.eventsRateLimit, maxEventsAPICallPerSecond, func() error {
+		started, err = client.StartQuery(ctx, &cloudwatchlogs.StartQueryInput{
+			LogGroupName: &groupName,
+			StartTime:    &start,
+			EndTime:      &end,
+			QueryString:  aws.String(query.String()),
+			Limit:        &limit,
+		})
+		return err //nolint:wrapcheck // wrapped below, once retries are exhausted
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start insights query: %w", err)
+	}
+	a.appLog.Debug("Insights query started",
+		slog.String("queryId", aws.ToString(started.QueryId)),
+		slog.Int64("start", start), slog.Int64("end", end))
+
+	pollInterval := a.cfg.Insights.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = defaultInsightsPollInterval
+	}
+	for {
+		var output *cloudwatchlogs.GetQueryResultsOutput
+		err := a.withRetry(ctx, "GetQueryResults", a.eventsRateLimit, maxEventsAPICallPerSecond, func() error {
+			var err error
+			output, err = client.GetQueryResults(ctx, &cloudwatchlogs.GetQueryResultsInput{QueryId: started.QueryId})
+			return err //nolint:wrapcheck // wrapped below, once retries are exhausted
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get insights query results: %w", err)
+		}
+		switch output.Status {
+		case types.QueryStatusComplete:
+			return output.Results, nil
+		case types.QueryStatusScheduled, types.QueryStatusRunning:
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrInsightsQueryFailed, output.Status)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("context cancelled: %w", ctx.Err())
+		case <-time.After(time.Duration(pollInterval) * time.Millisecond):
+		}
+	}
+}
+
+// insightsEvent converts a row of results to the event returned by FilterLogEvents.
+func insightsEvent(row []types.ResultField) (types.FilteredLogEvent, error) {
+	fields := make(map[string]string, len(row))
+	for _, field := range row {
+		fields[aws.ToString(field.Field)] = aws.ToString(field.Value)
+	}
+	for _, name := range []string{"@timestamp", "@message", "@logStream"} {
+		if _, ok := fields[name]; !ok {
+			return types.FilteredLogEvent{}, fmt.Errorf("%w: %s", ErrInsightsMissingField, name)
+		}
+	}
+	timestamp, err := time.ParseInLocation(insightsTimestampLayout, fields["@timestamp"], time.UTC)
+	if err != nil {
+		return types.FilteredLogEvent{}, fmt.Errorf("failed to parse insights timestamp: %w", err)
+	}
+	event := types.FilteredLogEvent{
+		Timestamp:     aws.Int64(timestamp.UnixMilli()),
+		Message:       aws.String(fields["@message"]),
+		LogStreamName: aws.String(fields["@logStream"]),
+	}
+	if ptr, ok := fields["@ptr"]; ok {
+		event.EventId = aws.String(ptr)
+	}
+	return event, nil
+}
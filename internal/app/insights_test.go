@@ -0,0 +1,156 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/sgaunet/awslogcheck/internal/configapp"
+)
+
+// mockInsightsClient returns the events in the time range of the queries, up to their limit.
+type mockInsightsClient struct {
+	events  []types.FilteredLogEvent
+	status  types.QueryStatus // Final status of the queries, Complete if empty
+	queries []*cloudwatchlogs.StartQueryInput
+	polls   map[string]int
+	omit    string // Field missing from the results
+}
+
+func (m *mockInsightsClient) StartQuery(_ context.Context, params *cloudwatchlogs.StartQueryInput,
+	_ ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error) {
+	m.queries = append(m.queries, params)
+	return &cloudwatchlogs.StartQueryOutput{QueryId: aws.String(fmt.Sprintf("query-%d", len(m.queries)-1))}, nil
+}
+
+func (m *mockInsightsClient) GetQueryResults(_ context.Context, params *cloudwatchlogs.GetQueryResultsInput,
+	_ ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+	if m.polls == nil {
+		m.polls = make(map[string]int)
+	}
+	queryID := aws.ToString(params.QueryId)
+	m.polls[queryID]++
+	if m.polls[queryID] == 1 {
+		return &cloudwatchlogs.GetQueryResultsOutput{Status: types.QueryStatusRunning}, nil
+	}
+	if m.status != "" {
+		return &cloudwatchlogs.GetQueryResultsOutput{Status: m.status}, nil
+	}
+	var index int
+	if _, err := fmt.Sscanf(queryID, "query-%d", &index); err != nil {
+		return nil, err
+	}
+	query := m.queries[index]
+	var results [][]types.ResultField
+	for _, event := range m.events {
+		second := aws.ToInt64(event.Timestamp) / 1000
+		if second < aws.ToInt64(query.StartTime) || second > aws.ToInt64(query.EndTime) {
+			continue
+		}
+		if len(results) == int(aws.ToInt32(query.Limit)) {
+			break
+		}
+		row := []types.ResultField{
+			{Field: aws.String("@timestamp"), Value: aws.String(
+				time.UnixMilli(aws.ToInt64(event.Timestamp)).UTC().Format(insightsTimestampLayout))},
+			{Field: aws.String("@message"), Value: event.Message},
+			{Field: aws.String("@logStream"), Value: event.LogStreamName},
+		}
+		for i, field := range row {
+			if aws.ToString(field.Field) == m.omit {
+				row = append(row[:i], row[i+1:]...)
+				break
+			}
+		}
+		results = append(results, row)
+	}
+	return &cloudwatchlogs.GetQueryResultsOutput{Status: types.QueryStatusComplete, Results: results}, nil
+}
+
+func newInsightsApp(cfg configapp.AppConfig) *App {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg.Backend = configapp.BackendInsights
+	cfg.Insights.PollInterval = 1
+	app := New(context.Background(), cfg, aws.Config{}, 3600, logger)
+	app.rules = ruleSet{ignore: mustCompileRules("^INFO:")}
+	return app
+}
+
+func TestParseAllEventsWithInsights(t *testing.T) {
+	app := newInsightsApp(configapp.AppConfig{Insights: configapp.InsightsConfig{
+		Query: "fields @timestamp, @message, @logStream | filter @logStream like /{{.LogGroup}}/",
+		Limit: 3,
+	}})
+	now := time.Now().Unix() * 1000
+	var events []types.FilteredLogEvent
+	for i := range 8 {
+		msg := fmt.Sprintf("ERROR: event %d", i)
+		if i%2 == 1 {
+			msg = fmt.Sprintf("INFO: event %d", i)
+		}
+		events = append(events, createLogEvent(now-int64(60-i*5)*1000, "stream-1", "pod-1", "app:latest", "app", msg))
+	}
+	client := &mockInsightsClient{events: events}
+
+	chLogLines := make(chan string, 1000)
+	printed, err := app.parseAllEventsWithInsightsClient(context.Background(), client, "test-group",
+		now-3600000, now, chLogLines)
+	close(chLogLines)
+	if err != nil {
+		t.Fatalf("parseAllEventsWithInsightsClient returned error: %v", err)
+	}
+	if printed != 4 {
+		t.Errorf("Expected 4 printed lines, got %d", printed)
+	}
+	var reported []string
+	for line := range chLogLines {
+		if strings.Contains(line, "ERROR: event") {
+			reported = append(reported, line[strings.Index(line, "ERROR: event"):][:len("ERROR: event 0")])
+		}
+	}
+	if strings.Join(reported, ",") != "ERROR: event 0,ERROR: event 2,ERROR: event 4,ERROR: event 6" {
+		t.Errorf("Unexpected reported events %v", reported)
+	}
+	if len(client.queries) < 3 {
+		t.Errorf("The window should have been split, got %d queries", len(client.queries))
+	}
+	if query := aws.ToString(client.queries[0].QueryString); !strings.Contains(query, "like /test-group/") {
+		t.Errorf("Query template not executed: %q", query)
+	}
+}
+
+func TestParseAllEventsWithInsightsErrors(t *testing.T) {
+	now := time.Now().Unix() * 1000
+	events := []types.FilteredLogEvent{createLogEvent(now-1000, "stream-1", "pod-1", "app:latest", "app", "ERROR: x")}
+	tests := []struct {
+		name     string
+		cfg      configapp.AppConfig
+		client   *mockInsightsClient
+		expected error
+	}{
+		{"failed query", configapp.AppConfig{}, &mockInsightsClient{events: events, status: types.QueryStatusFailed}, ErrInsightsQueryFailed},
+		{"missing field", configapp.AppConfig{}, &mockInsightsClient{events: events, omit: "@logStream"}, ErrInsightsMissingField},
+		{"invalid template", configapp.AppConfig{Insights: configapp.InsightsConfig{Query: "fields {{.Unknown"}}, &mockInsightsClient{}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newInsightsApp(tt.cfg)
+			_, err := app.parseAllEventsWithInsightsClient(context.Background(), tt.client, "test-group",
+				now-3600000, now, make(chan string, 1000))
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			if tt.expected != nil && !errors.Is(err, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
@@ -2,28 +2,35 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/sgaunet/awslogcheck/internal/configapp"
 	"github.com/sgaunet/calcdate/calcdate"
 )
 
-// Main function that parses every streams of loggroup a.cfg.LogGroup.
+// Main function that parses every streams of the log groups of the configuration.
 const logLinesChannelSize = 1000
 
 // LogCheck performs the main log checking process.
 func (a *App) LogCheck(ctx context.Context) error {
 	var wg sync.WaitGroup
 	chLogLines := make(chan string, logLinesChannelSize)
-	clientCloudwatchlogs := cloudwatchlogs.NewFromConfig(a.awscfg)
-	LogGroupExists := a.findLogGroup(ctx, clientCloudwatchlogs, a.cfg.LogGroup, "")
-	if !LogGroupExists {
-		err := fmt.Errorf("%w: %s", ErrLogGroupNotFound, a.cfg.LogGroup)
+	if a.cfg.Backend != "" && a.cfg.Backend != configapp.BackendFilter && !a.cfg.IsInsightsBackend() {
+		return fmt.Errorf("%w: %s", ErrUnknownBackend, a.cfg.Backend)
+	}
+	checks, errs := a.resolveTargets(ctx)
+	for _, err := range errs {
 		a.appLog.Error(err.Error())
-		return err
+	}
+	if len(checks) == 0 {
+		return errors.Join(errs...)
 	}
 
 	minTimeStampInMs, maxTimeStampInMs, err := a.GetTimeStampMsRangeofLastHour()
@@ -33,16 +40,81 @@ func (a *App) LogCheck(ctx context.Context) error {
 	a.appLog.Debug("minTimeStampsInMs", slog.Int64("value", minTimeStampInMs))
 	a.appLog.Debug("maxTimeStampsInMs", slog.Int64("value", maxTimeStampInMs))
 
+	// The notice only precedes the report if there are lines to report
+	notice := ""
+	if incomplete := a.waitForIngestion(ctx, checks, minTimeStampInMs, maxTimeStampInMs); len(incomplete) > 0 {
+		notice = incompleteNotice(incomplete)
+	}
+
 	wg.Add(1)
-	go a.collectLinesOfReportAndSendReport(ctx, &wg, chLogLines)
+	go func() {
+		defer wg.Done()
+		_ = a.collectLinesOfReportAndSendReport(ctx, chLogLines) // Logged, the next run sends a new report
+	}()
+	chReportLines, closeReportLines := withHeader(notice, chLogLines)
 
-	// Use the new FilterLogEvents API for better performance
-	_, err = a.parseAllEventsWithFilter(ctx, clientCloudwatchlogs,
-		a.cfg.LogGroup, minTimeStampInMs, maxTimeStampInMs, chLogLines)
+	cptLinePrinted := 0
+	for _, check := range checks {
+		// The log group is only printed when several are checked, and have lines to report
+		printed, err := a.checkLogGroup(ctx, check.client, check.groupName, minTimeStampInMs, maxTimeStampInMs,
+			check.header(len(checks)), chReportLines)
+		cptLinePrinted += printed
+		if err != nil {
+			a.appLog.Error("Failed to check log group", slog.String("target", check.target),
+				slog.String("groupName", check.groupName), slog.String("error", err.Error()))
+			errs = append(errs, err)
+		}
+	}
+	if cptLinePrinted > 0 && a.cfg.RuleStats.Report {
+		a.outputRuleStats(chReportLines)
+	}
+	closeReportLines()
 	close(chLogLines)
 
 	wg.Wait()
-	return err
+	if statsErr := a.saveRuleStats(time.Now()); statsErr != nil {
+		a.appLog.Error("Failed to save rule statistics", slog.String("error", statsErr.Error()))
+	}
+	return errors.Join(errs...)
+}
+
+// checkLogGroup sends the lines to report of a log group, preceded by header if there are any.
+func (a *App) checkLogGroup(ctx context.Context, client *cloudwatchlogs.Client, groupName string,
+	minTimeStampInMs, maxTimeStampInMs int64, header string, chLogLines chan<- string) (int, error) {
+	chGroupLines, closeGroupLines := withHeader(header, chLogLines)
+	var cptLinePrinted int
+	var err error
+	if a.cfg.IsInsightsBackend() {
+		cptLinePrinted, err = a.parseAllEventsWithInsightsClient(ctx, client,
+			groupName, minTimeStampInMs, maxTimeStampInMs, chGroupLines)
+	} else {
+		// Use the new FilterLogEvents API for better performance
+		cptLinePrinted, err = a.parseAllEventsWithFilter(ctx, client,
+			groupName, minTimeStampInMs, maxTimeStampInMs, chGroupLines)
+	}
+	closeGroupLines()
+	return cptLinePrinted, err
+}
+
+// withHeader returns a channel forwarding its lines to chLogLines, preceded by header if there
+// are any, and the function closing it once its lines are forwarded.
+func withHeader(header string, chLogLines chan<- string) (chan<- string, func()) {
+	chLines := make(chan string, logLinesChannelSize)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for line := range chLines {
+			if header != "" {
+				chLogLines <- header
+				header = ""
+			}
+			chLogLines <- line
+		}
+	}()
+	return chLines, func() {
+		close(chLines)
+		<-done
+	}
 }
 
 // GetTimeStampMsRangeofLastHour returns timestamps for the last hour in milliseconds.
@@ -63,15 +135,16 @@ func (a *App) GetTimeStampMsRangeofLastHour() (int64, int64, error) {
 }
 
 // collectLinesOfReportAndSendReport collect lines of report and send report if file is overs 2MB.
-func (a *App) collectLinesOfReportAndSendReport(_ context.Context, wg *sync.WaitGroup, chLines <-chan string) {
-	defer wg.Done()
+// The errors of the sendings are logged, and returned.
+func (a *App) collectLinesOfReportAndSendReport(_ context.Context, chLines <-chan string) error {
 	emptyReport := true // used to know if report has to be sent or not
 	reportFilename := "/tmp/report.html"
 	f, err := a.createReportFile(reportFilename)
 	if err != nil {
-		return
+		return err
 	}
 	sizeFile := 0
+	var sendErrs []error
 
 	for {
 		line, ok := <-chLines
@@ -80,13 +153,21 @@ func (a *App) collectLinesOfReportAndSendReport(_ context.Context, wg *sync.Wait
 			break
 		}
 		emptyReport = false
-		if f, sizeFile = a.writeLineToReport(f, line, sizeFile, reportFilename); f == nil {
-			return
+		var sendErr error
+		f, sizeFile, sendErr = a.writeLineToReport(f, line, sizeFile, reportFilename)
+		if sendErr != nil {
+			sendErrs = append(sendErrs, sendErr)
+		}
+		if f == nil {
+			return errors.Join(sendErrs...)
 		}
 	}
 
 	a.closeReportFile(f)
-	a.sendReportIfNotEmpty(emptyReport, reportFilename)
+	if err := a.sendReportIfNotEmpty(emptyReport, reportFilename); err != nil {
+		sendErrs = append(sendErrs, err)
+	}
+	return errors.Join(sendErrs...)
 }
 
 func (a *App) createReportFile(filename string) (*os.File, error) {
@@ -100,24 +181,26 @@ func (a *App) createReportFile(filename string) (*os.File, error) {
 	return f, nil
 }
 
-func (a *App) writeLineToReport(f *os.File, line string, sizeFile int, filename string) (*os.File, int) {
+// writeLineToReport writes line to the report, and sends it if it's too large. It returns the
+// error of the sending, and a nil file if the next report can't be created.
+func (a *App) writeLineToReport(f *os.File, line string, sizeFile int, filename string) (*os.File, int, error) {
 	if _, err := f.WriteString(line); err != nil {
 		a.appLog.Error("Failed to write to report file", slog.String("error", err.Error()))
-		return f, sizeFile
+		return f, sizeFile, nil
 	}
 	sizeFile += len(line)
 
 	if sizeFile > a.cfg.SMTPConfig.MaxReportSize {
 		a.appLog.Debug("size > MaxReportSize")
 		a.closeReportFile(f)
-		a.sendAndRemoveReport(filename)
+		sendErr := a.sendAndRemoveReport(filename)
 		newFile, err := a.createReportFile(filename)
 		if err != nil {
-			return nil, 0
+			return nil, 0, sendErr
 		}
-		return newFile, 0
+		return newFile, 0, sendErr
 	}
-	return f, sizeFile
+	return f, sizeFile, nil
 }
 
 func (a *App) closeReportFile(f *os.File) {
@@ -126,22 +209,25 @@ func (a *App) closeReportFile(f *os.File) {
 	}
 }
 
-func (a *App) sendAndRemoveReport(filename string) {
+func (a *App) sendAndRemoveReport(filename string) error {
 	a.appLog.Debug("send report *")
-	if err := a.SendReport(filename); err != nil {
-		a.appLog.Error("Error occurred", slog.String("error", err.Error()))
+	sendErr := a.SendReport(filename)
+	if sendErr != nil {
+		a.appLog.Error("Error occurred", slog.String("error", sendErr.Error()))
 	}
 	a.appLog.Debug("remove report")
 	if err := os.Remove(filename); err != nil {
 		a.appLog.Error("Error occurred", slog.String("error", err.Error()))
 	}
+	return sendErr
 }
 
-func (a *App) sendReportIfNotEmpty(emptyReport bool, filename string) {
+func (a *App) sendReportIfNotEmpty(emptyReport bool, filename string) error {
+	var sendErr error
 	if !emptyReport {
 		a.appLog.Debug("send report")
-		if err := a.SendReport(filename); err != nil {
-			a.appLog.Error("Error occurred", slog.String("error", err.Error()))
+		if sendErr = a.SendReport(filename); sendErr != nil {
+			a.appLog.Error("Error occurred", slog.String("error", sendErr.Error()))
 		}
 	}
 	a.appLog.Debug("remove report")
@@ -150,4 +236,52 @@ func (a *App) sendReportIfNotEmpty(emptyReport bool, filename string) {
 	}
 	a.appLog.Debug("report filepath", slog.String("path", filename))
 	a.appLog.Debug("end")
+	return sendErr
+}
+
+// reportBatch sends the lines to report of batch, the streams by log group, preceded by the
+// header of their log group. It returns the error of the sending of the report.
+func (a *App) reportBatch(batch map[string]map[string]*streamEvents, headers map[string]string) error {
+	chLogLines := make(chan string, logLinesChannelSize)
+	var wg sync.WaitGroup
+	var sendErr error
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		sendErr = a.collectLinesOfReportAndSendReport(context.Background(), chLogLines)
+	}()
+	printed := a.writeBatch(batch, headers, chLogLines)
+	close(chLogLines)
+	wg.Wait()
+	a.appLog.Debug("Batch reported", slog.Int("linesPrinted", printed))
+	if err := a.saveRuleStats(time.Now()); err != nil {
+		a.appLog.Error("Failed to save rule statistics", slog.String("error", err.Error()))
+	}
+	return sendErr
+}
+
+// writeBatch prints the lines to report of batch, and returns their number.
+func (a *App) writeBatch(batch map[string]map[string]*streamEvents, headers map[string]string,
+	chLogLines chan<- string) int {
+	groupKeys := make([]string, 0, len(batch))
+	for groupKey := range batch {
+		groupKeys = append(groupKeys, groupKey)
+	}
+	sort.Strings(groupKeys)
+	cptLinePrinted := 0
+	for _, groupKey := range groupKeys {
+		streamGroups := batch[groupKey]
+		if a.multiline != nil {
+			a.flushMultilineEvents(streamGroups)
+		}
+		if len(a.getReportedStreamKeys(streamGroups)) == 0 {
+			continue
+		}
+		if header := headers[groupKey]; header != "" {
+			chLogLines <- header
+		}
+		printed, _ := a.outputStreamEvents(streamGroups, chLogLines, 0)
+		cptLinePrinted += printed
+	}
+	return cptLinePrinted
 }
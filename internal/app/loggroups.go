@@ -0,0 +1,199 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/sgaunet/awslogcheck/internal/configapp"
+)
+
+// CloudWatchLogsGroupsClient interface for testing.
+type CloudWatchLogsGroupsClient interface {
+	DescribeLogGroups(ctx context.Context,
+		params *cloudwatchlogs.DescribeLogGroupsInput,
+		optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogGroupsOutput, error)
+	ListTagsForResource(ctx context.Context,
+		params *cloudwatchlogs.ListTagsForResourceInput,
+		optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.ListTagsForResourceOutput, error)
+}
+
+// logGroupResolver resolves the log group selectors, listing the log groups once per prefix.
+type logGroupResolver struct {
+	app    *App
+	client CloudWatchLogsGroupsClient
+	groups map[string][]types.LogGroup  // By prefix
+	tags   map[string]map[string]string // By log group ARN
+}
+
+// resolveLogGroups returns the log groups matching selectors, sorted by name.
+func (a *App) resolveLogGroups(ctx context.Context, client CloudWatchLogsGroupsClient,
+	selectors []configapp.LogGroupSelector) ([]types.LogGroup, error) {
+	if len(selectors) == 0 {
+		return nil, fmt.Errorf("%w: no loggroup configured", ErrLogGroupNotFound)
+	}
+	r := &logGroupResolver{
+		app:    a,
+		client: client,
+		groups: make(map[string][]types.LogGroup),
+		tags:   make(map[string]map[string]string),
+	}
+	found := make(map[string]types.LogGroup)
+	for _, selector := range selectors {
+		groups, err := r.resolve(ctx, selector)
+		if err != nil {
+			return nil, err
+		}
+		if len(groups) == 0 {
+			a.appLog.Warn("No log group matches the selector", slog.Any("selector", selector))
+		}
+		for _, group := range groups {
+			found[aws.ToString(group.LogGroupName)] = group
+		}
+	}
+	if len(found) == 0 {
+		return nil, fmt.Errorf("%w: no log group matches the selectors", ErrLogGroupNotFound)
+	}
+	names := make([]string, 0, len(found))
+	for name := range found {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	a.appLog.Debug("Log groups resolved", slog.Any("logGroups", names))
+	groups := make([]types.LogGroup, 0, len(names))
+	for _, name := range names {
+		groups = append(groups, found[name])
+	}
+	return groups, nil
+}
+
+// resolve returns the log groups matching selector.
+func (r *logGroupResolver) resolve(ctx context.Context, selector configapp.LogGroupSelector) ([]types.LogGroup, error) {
+	if selector.Name == "" && selector.Prefix == "" && selector.Glob == "" && selector.Regexp == "" &&
+		len(selector.Tags) == 0 {
+		return nil, fmt.Errorf("%w: empty selector", ErrInvalidLogGroupSelector)
+	}
+	if selector.Glob != "" {
+		if _, err := path.Match(selector.Glob, ""); err != nil {
+			return nil, fmt.Errorf("%w: glob %q: %w", ErrInvalidLogGroupSelector, selector.Glob, err)
+		}
+	}
+	var re *regexp.Regexp
+	if selector.Regexp != "" {
+		var err error
+		if re, err = regexp.Compile(selector.Regexp); err != nil {
+			return nil, fmt.Errorf("%w: regexp %q: %w", ErrInvalidLogGroupSelector, selector.Regexp, err)
+		}
+	}
+
+	groups, err := r.list(ctx, selectorPrefix(selector))
+	if err != nil {
+		return nil, err
+	}
+	var matching []types.LogGroup
+	for _, group := range groups {
+		name := aws.ToString(group.LogGroupName)
+		if selector.Name != "" && name != selector.Name ||
+			!strings.HasPrefix(name, selector.Prefix) ||
+			re != nil && !re.MatchString(name) {
+			continue
+		}
+		if selector.Glob != "" {
+			if matched, _ := path.Match(selector.Glob, name); !matched {
+				continue
+			}
+		}
+		if len(selector.Tags) > 0 {
+			matched, err := r.matchTags(ctx, group, selector.Tags)
+			if err != nil {
+				return nil, err
+			}
+			if !matched {
+				continue
+			}
+		}
+		matching = append(matching, group)
+	}
+	return matching, nil
+}
+
+// selectorPrefix returns the longest prefix of the names matching selector, to filter the listing.
+func selectorPrefix(selector configapp.LogGroupSelector) string {
+	if selector.Name != "" {
+		return selector.Name
+	}
+	prefix := selector.Prefix
+	if selector.Glob != "" {
+		globPrefix := selector.Glob
+		if i := strings.IndexAny(globPrefix, `*?[\`); i >= 0 {
+			globPrefix = globPrefix[:i]
+		}
+		if len(globPrefix) > len(prefix) {
+			prefix = globPrefix
+		}
+	}
+	return prefix
+}
+
+// list returns the log groups whose name starts with prefix.
+func (r *logGroupResolver) list(ctx context.Context, prefix string) ([]types.LogGroup, error) {
+	if groups, ok := r.groups[prefix]; ok {
+		return groups, nil
+	}
+	var groups []types.LogGroup
+	var params cloudwatchlogs.DescribeLogGroupsInput
+	if prefix != "" {
+		params.LogGroupNamePrefix = &prefix
+	}
+	for {
+		var res *cloudwatchlogs.DescribeLogGroupsOutput
+		err := r.app.withRetry(ctx, "DescribeLogGroups", r.app.logGroupRateLimit, maxLogGroupAPICallPerSecond, func() error {
+			var err error
+			res, err = r.client.DescribeLogGroups(ctx, &params)
+			return err //nolint:wrapcheck // wrapped below, once retries are exhausted
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe log groups: %w", err)
+		}
+		groups = append(groups, res.LogGroups...)
+		if res.NextToken == nil {
+			break
+		}
+		params.NextToken = res.NextToken
+	}
+	r.groups[prefix] = groups
+	return groups, nil
+}
+
+// matchTags checks if the log group has the tags, an empty value matching any value.
+func (r *logGroupResolver) matchTags(ctx context.Context, group types.LogGroup, tags map[string]string) (bool, error) {
+	arn := aws.ToString(group.LogGroupArn)
+	groupTags, ok := r.tags[arn]
+	if !ok {
+		var res *cloudwatchlogs.ListTagsForResourceOutput
+		err := r.app.withRetry(ctx, "ListTagsForResource", r.app.logGroupRateLimit, maxLogGroupAPICallPerSecond, func() error {
+			var err error
+			res, err = r.client.ListTagsForResource(ctx, &cloudwatchlogs.ListTagsForResourceInput{ResourceArn: &arn})
+			return err //nolint:wrapcheck // wrapped below, once retries are exhausted
+		})
+		if err != nil {
+			return false, fmt.Errorf("failed to list the tags of %s: %w", aws.ToString(group.LogGroupName), err)
+		}
+		groupTags = res.Tags
+		r.tags[arn] = groupTags
+	}
+	for key, value := range tags {
+		groupValue, ok := groupTags[key]
+		if !ok || value != "" && groupValue != value {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,159 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/smithy-go"
+	"github.com/sgaunet/awslogcheck/internal/configapp"
+)
+
+// mockGroupsClient lists its log groups two per page, filtered by prefix.
+type mockGroupsClient struct {
+	groups   []string
+	tags     map[string]map[string]string // By log group name
+	err      error
+	prefixes []string // LogGroupNamePrefix of every listing
+	tagCalls int
+}
+
+func (m *mockGroupsClient) DescribeLogGroups(_ context.Context, params *cloudwatchlogs.DescribeLogGroupsInput,
+	_ ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogGroupsOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	offset := 0
+	if params.NextToken != nil {
+		offset, _ = strconv.Atoi(*params.NextToken)
+	} else {
+		m.prefixes = append(m.prefixes, aws.ToString(params.LogGroupNamePrefix))
+	}
+	var matching []string
+	for _, name := range m.groups {
+		if strings.HasPrefix(name, aws.ToString(params.LogGroupNamePrefix)) {
+			matching = append(matching, name)
+		}
+	}
+	end := min(offset+2, len(matching))
+	output := &cloudwatchlogs.DescribeLogGroupsOutput{}
+	for _, name := range matching[offset:end] {
+		output.LogGroups = append(output.LogGroups, types.LogGroup{
+			LogGroupName: aws.String(name),
+			LogGroupArn:  aws.String("arn:aws:logs:eu-west-3:123456789012:log-group:" + name),
+		})
+	}
+	if end < len(matching) {
+		output.NextToken = aws.String(strconv.Itoa(end))
+	}
+	return output, nil
+}
+
+func (m *mockGroupsClient) ListTagsForResource(_ context.Context, params *cloudwatchlogs.ListTagsForResourceInput,
+	_ ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.ListTagsForResourceOutput, error) {
+	m.tagCalls++
+	name := aws.ToString(params.ResourceArn)[strings.Index(aws.ToString(params.ResourceArn), "log-group:")+len("log-group:"):]
+	return &cloudwatchlogs.ListTagsForResourceOutput{Tags: m.tags[name]}, nil
+}
+
+func TestResolveLogGroups(t *testing.T) {
+	groups := []string{
+		"/aws/containerinsights/dev-EKS/application",
+		"/aws/containerinsights/dev-EKS/host",
+		"/aws/containerinsights/prod-EKS/application",
+		"/aws/lambda/function",
+		"/custom/app",
+	}
+	tags := map[string]map[string]string{
+		"/aws/containerinsights/prod-EKS/application": {"env": "prod", "team": "core"},
+		"/aws/lambda/function":                        {"env": "prod"},
+		"/custom/app":                                 {"env": "dev", "awslogcheck": ""},
+	}
+	tests := []struct {
+		name      string
+		cfg       configapp.AppConfig
+		expected  []string
+		prefixes  []string
+		expectErr error
+	}{
+		{
+			"exact name", configapp.AppConfig{LogGroup: "/aws/lambda/function"},
+			[]string{"/aws/lambda/function"}, []string{"/aws/lambda/function"}, nil,
+		},
+		{
+			"prefix", configapp.AppConfig{LogGroups: []configapp.LogGroupSelector{{Prefix: "/aws/containerinsights/"}}},
+			groups[:3], []string{"/aws/containerinsights/"}, nil,
+		},
+		{
+			"glob", configapp.AppConfig{LogGroups: []configapp.LogGroupSelector{{Glob: "/aws/containerinsights/*/application"}}},
+			[]string{groups[0], groups[2]}, []string{"/aws/containerinsights/"}, nil,
+		},
+		{
+			"regexp and tags", configapp.AppConfig{LogGroups: []configapp.LogGroupSelector{
+				{Regexp: "application$", Tags: map[string]string{"env": "prod"}},
+				{Tags: map[string]string{"awslogcheck": ""}},
+			}},
+			[]string{groups[2], "/custom/app"}, []string{""}, nil,
+		},
+		{
+			"several selectors", configapp.AppConfig{LogGroup: "/custom/app", LogGroups: []configapp.LogGroupSelector{
+				{Prefix: "/aws/lambda/"}, {Name: "/custom/app"},
+			}},
+			[]string{"/aws/lambda/function", "/custom/app"}, []string{"/custom/app", "/aws/lambda/"}, nil,
+		},
+		{"not found", configapp.AppConfig{LogGroup: "/unknown"}, nil, nil, ErrLogGroupNotFound},
+		{"nothing configured", configapp.AppConfig{}, nil, nil, ErrLogGroupNotFound},
+		{
+			"empty selector", configapp.AppConfig{LogGroups: []configapp.LogGroupSelector{{}}},
+			nil, nil, ErrInvalidLogGroupSelector,
+		},
+		{
+			"invalid regexp", configapp.AppConfig{LogGroups: []configapp.LogGroupSelector{{Regexp: "(broken"}}},
+			nil, nil, ErrInvalidLogGroupSelector,
+		},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := New(context.Background(), tt.cfg, aws.Config{}, 3600, logger)
+			client := &mockGroupsClient{groups: groups, tags: tags}
+			groups, err := app.resolveLogGroups(context.Background(), client, tt.cfg.GetLogGroupSelectors())
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Errorf("Expected %v, got %v", tt.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveLogGroups returned error: %v", err)
+			}
+			var names []string
+			for _, group := range groups {
+				names = append(names, aws.ToString(group.LogGroupName))
+			}
+			if strings.Join(names, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Expected %v, got %v", tt.expected, names)
+			}
+			if strings.Join(client.prefixes, ",") != strings.Join(tt.prefixes, ",") {
+				t.Errorf("Expected listings of %v, got %v", tt.prefixes, client.prefixes)
+			}
+		})
+	}
+}
+
+func TestResolveLogGroupsError(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	app := New(context.Background(), configapp.AppConfig{LogGroup: "/a"}, aws.Config{}, 3600, logger)
+	denied := &smithy.GenericAPIError{Code: "AccessDeniedException"}
+	if _, err := app.resolveLogGroups(context.Background(), &mockGroupsClient{err: denied},
+		app.cfg.GetLogGroupSelectors()); !errors.Is(err, denied) {
+		t.Errorf("Expected the error of DescribeLogGroups, got %v", err)
+	}
+}
@@ -0,0 +1,163 @@
+package app
+
+import (
+	"log/slog"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+// Built-in detectors of the multiline configuration.
+const (
+	detectorJava   = "java"
+	detectorPython = "python"
+	detectorGo     = "go"
+)
+
+const defaultMultilineMaxLines = 500
+
+var (
+	javaContinuation = regexp.MustCompile(`^(\s+at \S|\s+\.\.\. \d+ (more|common frames omitted)|Caused by: |\s+Suppressed: |` +
+		`([a-zA-Z_$][\w$]*\.)+[\w$]*(Exception|Error|Throwable)(: |$))`)
+	pythonTracebackStart = regexp.MustCompile(`^Traceback \(most recent call last\):`)
+	goPanicStart         = regexp.MustCompile(`^(panic: |fatal error: )`)
+	goPanicContinuation  = regexp.MustCompile(
+		`^(\s|$|goroutine \d+ \[|created by |\[signal |\[recovered\]|exit status |[\w.*/()\[\]{}-]+\(.*\)$)`)
+)
+
+// multiline joins the lines of a multi-line event (stack traces) before the
+// rules are applied.
+type multiline struct {
+	start     *regexp.Regexp // A line matching starts a new event
+	detectors map[string]bool
+	maxLines  int
+}
+
+// pendingEvent is a multi-line event being assembled.
+type pendingEvent struct {
+	lineOfLog fluentDockerLog // Metadata of the first line
+	event     types.FilteredLogEvent
+	lines     []string
+	trace     string // Python or Go trace in progress
+	indented  bool   // Python: the frames have been seen, the next line ends the trace
+}
+
+// compileMultiline returns the multiline settings, nil if disabled. An incorrect start
+// pattern is logged and ignored.
+func (a *App) compileMultiline() *multiline {
+	cfg := a.cfg.Multiline
+	if cfg.StartPattern == "" && len(cfg.Detectors) == 0 {
+		return nil
+	}
+	m := &multiline{detectors: make(map[string]bool), maxLines: cfg.MaxLines}
+	if m.maxLines <= 0 {
+		m.maxLines = defaultMultilineMaxLines
+	}
+	if cfg.StartPattern != "" {
+		re, err := regexp.Compile(cfg.StartPattern)
+		if err != nil {
+			a.appLog.Error("multiline start pattern is incorrect",
+				slog.String("pattern", cfg.StartPattern),
+				slog.String("error", err.Error()))
+		} else {
+			m.start = re
+		}
+	}
+	for _, detector := range cfg.Detectors {
+		switch detector {
+		case detectorJava, detectorPython, detectorGo:
+			m.detectors[detector] = true
+		default:
+			a.appLog.Error("unknown multiline detector", slog.String("detector", detector))
+		}
+	}
+	if m.start == nil && len(m.detectors) == 0 {
+		return nil
+	}
+	return m
+}
+
+// isContinuation checks if line belongs to the pending event, and updates its trace state.
+func (m *multiline) isContinuation(pending *pendingEvent, line string) bool {
+	if len(pending.lines) >= m.maxLines {
+		return false
+	}
+	switch pending.trace {
+	case detectorPython:
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			pending.indented = true
+			return true
+		}
+		// The exception line ends the traceback
+		pending.trace = ""
+		return pending.indented
+	case detectorGo:
+		return goPanicContinuation.MatchString(line)
+	}
+	if m.detectors[detectorJava] && javaContinuation.MatchString(line) {
+		return true
+	}
+	if m.detectors[detectorPython] && pythonTracebackStart.MatchString(line) {
+		return false
+	}
+	if m.detectors[detectorGo] && goPanicStart.MatchString(line) {
+		return false
+	}
+	return m.start != nil && !m.start.MatchString(line)
+}
+
+// newPendingEvent starts a multi-line event with its first line.
+func (m *multiline) newPendingEvent(lineOfLog fluentDockerLog, event types.FilteredLogEvent, line string) *pendingEvent {
+	pending := &pendingEvent{lineOfLog: lineOfLog, event: event, lines: []string{line}}
+	switch {
+	case m.detectors[detectorPython] && pythonTracebackStart.MatchString(line):
+		pending.trace = detectorPython
+	case m.detectors[detectorGo] && goPanicStart.MatchString(line):
+		pending.trace = detectorGo
+	}
+	return pending
+}
+
+// addMultilineEvent adds a line to the pending event of its container, and
+// processes the previous event of the container when the line starts a new one.
+func (a *App) addMultilineEvent(lineOfLog fluentDockerLog, stream *streamEvents, event types.FilteredLogEvent) {
+	line := strings.TrimRight(lineOfLog.Log, "\r\n")
+	key := lineOfLog.Kubernetes.ContainerName
+	if stream.pending == nil {
+		stream.pending = make(map[string]*pendingEvent)
+	}
+	pending, ok := stream.pending[key]
+	if ok && a.multiline.isContinuation(pending, line) {
+		pending.lines = append(pending.lines, line)
+		return
+	}
+	if ok {
+		a.processPendingEvent(pending, stream)
+	}
+	stream.pending[key] = a.multiline.newPendingEvent(lineOfLog, event, line)
+}
+
+// flushMultilineEvents processes the events still pending at the end of the period.
+func (a *App) flushMultilineEvents(streamGroups map[string]*streamEvents) {
+	for _, streamKey := range a.getSortedStreamKeys(streamGroups) {
+		stream := streamGroups[streamKey]
+		containers := make([]string, 0, len(stream.pending))
+		for container := range stream.pending {
+			containers = append(containers, container)
+		}
+		sort.Strings(containers)
+		for _, container := range containers {
+			a.processPendingEvent(stream.pending[container], stream)
+		}
+		stream.pending = nil
+	}
+}
+
+// processPendingEvent classifies a multi-line event as a whole.
+func (a *App) processPendingEvent(pending *pendingEvent, stream *streamEvents) {
+	lineOfLog := pending.lineOfLog
+	lineOfLog.Log = strings.Join(pending.lines, "\n")
+	a.processMessage(lineOfLog, stream, pending.event)
+}
@@ -0,0 +1,139 @@
+package app
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/sgaunet/awslogcheck/internal/configapp"
+)
+
+// runMultiline returns the reported events of lines, with the ignore rules ^INFO: and ^WARN: retrying.
+func runMultiline(t *testing.T, cfg configapp.MultilineConfig, lines ...string) []string {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	app := New(context.Background(), configapp.AppConfig{Multiline: cfg}, aws.Config{}, 3600, logger)
+	app.rules = ruleSet{ignore: mustCompileRules("^INFO:", "^WARN: retrying")}
+
+	now := time.Now().Unix() * 1000
+	events := make([]types.FilteredLogEvent, 0, len(lines))
+	for i, line := range lines {
+		events = append(events, createLogEvent(now-int64(len(lines)-i)*1000, "stream-1", "pod-1", "app:latest", "app", line+"\n"))
+	}
+	// A sidecar line in the middle of the trace must not break it
+	events = append(events[:2:2], append([]types.FilteredLogEvent{
+		createLogEvent(now-int64(len(lines)-1)*1000, "stream-1", "pod-1", "proxy:latest", "proxy", "proxy: ERROR upstream\n"),
+	}, events[2:]...)...)
+
+	chLogLines := make(chan string, 1000)
+	go func() {
+		_, err := app.parseAllEventsWithFilterClient(context.Background(), &mockCloudWatchClient{events: events, pageSize: 3},
+			"test-group", now-3600000, now, chLogLines)
+		if err != nil {
+			t.Errorf("parseAllEventsWithFilterClient returned error: %v", err)
+		}
+		close(chLogLines)
+	}()
+	var reported []string
+	for line := range chLogLines {
+		if strings.Contains(line, " UTC: ") {
+			reported = append(reported, line)
+		}
+	}
+	return reported
+}
+
+func TestMultilineDetectors(t *testing.T) {
+	cfg := configapp.MultilineConfig{Detectors: []string{"java", "python", "go"}}
+	reported := runMultiline(t, cfg,
+		"ERROR: request failed",
+		"java.lang.IllegalStateException: boom",
+		"\tat com.example.Service.run(Service.java:42)",
+		"\t... 12 more",
+		"Caused by: java.io.IOException: reset",
+		"INFO: next request",
+		"WARN: retrying",
+		"\tat com.example.Client.call(Client.java:7)",
+		"Traceback (most recent call last):",
+		`  File "app.py", line 3, in <module>`,
+		"ValueError: bad value",
+		"panic: runtime error: index out of range",
+		"",
+		"goroutine 1 [running]:",
+		"main.main()",
+		"\t/app/main.go:12 +0x1d",
+		"ERROR: after the panic",
+	)
+
+	expected := []string{
+		"ERROR: request failed<br>\njava.lang.IllegalStateException: boom<br>\n\tat com.example.Service.run",
+		"proxy: ERROR upstream",
+		"Traceback (most recent call last):<br>\n  File \"app.py\", line 3, in <module><br>\nValueError: bad value",
+		"panic: runtime error: index out of range<br>\n<br>\ngoroutine 1 [running]:<br>\nmain.main()<br>\n\t/app/main.go:12 +0x1d",
+		"ERROR: after the panic",
+	}
+	if len(reported) != len(expected) {
+		t.Fatalf("Expected %d events, got %d:\n%s", len(expected), len(reported), strings.Join(reported, "\n"))
+	}
+	for i, event := range expected {
+		if !strings.Contains(reported[i], event) {
+			t.Errorf("Event %d: expected %q in %q", i, event, reported[i])
+		}
+	}
+	if !strings.Contains(reported[0], "Caused by: java.io.IOException: reset") {
+		t.Error("Caused by should belong to the java trace")
+	}
+}
+
+func TestMultilineStartPattern(t *testing.T) {
+	cfg := configapp.MultilineConfig{StartPattern: `^(INFO|WARN|ERROR):`, MaxLines: 3}
+	reported := runMultiline(t, cfg,
+		"ERROR: first",
+		"  detail 1",
+		"  detail 2",
+		"  detail 3",
+		"INFO: ignored",
+		"  ignored detail",
+	)
+	if len(reported) != 3 {
+		t.Fatalf("Expected 3 events, got %d:\n%s", len(reported), strings.Join(reported, "\n"))
+	}
+	if !strings.Contains(reported[0], "ERROR: first<br>\n  detail 1<br>\n  detail 2") {
+		t.Errorf("Unexpected first event %q", reported[0])
+	}
+	if !strings.Contains(reported[2], "  detail 3") {
+		t.Errorf("maxlines should start a new event, got %q", reported[2])
+	}
+	for _, event := range reported {
+		if strings.Contains(event, "ignored detail") {
+			t.Error("The lines of an ignored event should be ignored")
+		}
+	}
+}
+
+func TestCompileMultiline(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	tests := []struct {
+		name    string
+		cfg     configapp.MultilineConfig
+		enabled bool
+	}{
+		{"disabled", configapp.MultilineConfig{}, false},
+		{"invalid pattern only", configapp.MultilineConfig{StartPattern: "(broken"}, false},
+		{"unknown detector only", configapp.MultilineConfig{Detectors: []string{"ruby"}}, false},
+		{"detector", configapp.MultilineConfig{Detectors: []string{"go"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := New(context.Background(), configapp.AppConfig{Multiline: tt.cfg}, aws.Config{}, 3600, logger)
+			if (app.multiline != nil) != tt.enabled {
+				t.Errorf("Expected enabled=%v", tt.enabled)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/sgaunet/awslogcheck/internal/logsource"
+)
+
+// NewSource returns the source of input, in format or the format given by its extension.
+func (a *App) NewSource(input, format string) (logsource.Source, error) {
+	source, err := logsource.New(input, logsource.Options{
+		Format: format,
+		S3:     a.newS3Client(a.awscfg),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log source: %w", err)
+	}
+	return source, nil
+}
+
+// CheckSources applies the rules to the events of sources, and returns the number of lines
+// reported. The report is written to w, or sent by mail if w is nil.
+func (a *App) CheckSources(ctx context.Context, sources []logsource.Source, w io.Writer) (int, error) {
+	return a.reportTo(ctx, w, func(chLogLines chan<- string) (int, []error) {
+		var errs []error
+		cptLinePrinted := 0
+		for _, source := range sources {
+			// The source is only printed when several are checked, and have lines to report
+			header := ""
+			if len(sources) > 1 {
+				header = sourceHeader(source.Name())
+			}
+			chSourceLines, closeSourceLines := withHeader(header, chLogLines)
+			printed, err := a.checkSource(ctx, source, chSourceLines)
+			closeSourceLines()
+			cptLinePrinted += printed
+			if err != nil {
+				a.appLog.Error("Failed to check log source", slog.String("source", source.Name()),
+					slog.String("error", err.Error()))
+				errs = append(errs, err)
+			}
+		}
+		return cptLinePrinted, errs
+	})
+}
+
+// reportTo calls check with the channel of the lines of the report, written to w or sent by
+// mail if w is nil, and returns the number of lines reported by check.
+func (a *App) reportTo(ctx context.Context, w io.Writer,
+	check func(chLogLines chan<- string) (int, []error)) (int, error) {
+	chLogLines := make(chan string, logLinesChannelSize)
+	var wg sync.WaitGroup
+	var writeErr error
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if w == nil {
+			writeErr = a.collectLinesOfReportAndSendReport(ctx, chLogLines)
+		} else {
+			writeErr = writeReport(w, chLogLines)
+		}
+	}()
+
+	cptLinePrinted, errs := check(chLogLines)
+	if cptLinePrinted > 0 && a.cfg.RuleStats.Report {
+		a.outputRuleStats(chLogLines)
+	}
+	close(chLogLines)
+
+	wg.Wait()
+	if writeErr != nil {
+		errs = append(errs, writeErr)
+	}
+	if statsErr := a.saveRuleStats(time.Now()); statsErr != nil {
+		a.appLog.Error("Failed to save rule statistics", slog.String("error", statsErr.Error()))
+	}
+	return cptLinePrinted, errors.Join(errs...)
+}
+
+// checkSource sends the lines to report of a source, and returns their number.
+func (a *App) checkSource(ctx context.Context, source logsource.Source, chLogLines chan<- string) (int, error) {
+	streamGroups := make(map[string]*streamEvents)
+	eventCount := 0
+	err := source.Read(ctx, func(events []types.FilteredLogEvent) error {
+		a.processEventsInPage(events, streamGroups, &eventCount)
+		return nil
+	})
+	defer a.releaseStreams(streamGroups)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", source.Name(), err)
+	}
+	if a.multiline != nil {
+		a.flushMultilineEvents(streamGroups)
+	}
+	a.appLog.Debug("Completed log source processing",
+		slog.String("source", source.Name()),
+		slog.Int("totalEvents", eventCount),
+		slog.Int("streams", len(streamGroups)))
+	return a.outputStreamEvents(streamGroups, chLogLines, eventCount)
+}
+
+// sourceHeader returns the header of a log source in the report.
+func sourceHeader(name string) string {
+	return "<h1>Source: " + html.EscapeString(name) + "</h1>\n"
+}
+
+// writeReport writes the lines of chLines to w, and returns the first error. The channel is
+// drained in any case.
+func writeReport(w io.Writer, chLines <-chan string) error {
+	var err error
+	for line := range chLines {
+		if err == nil {
+			if _, err = io.WriteString(w, line); err != nil {
+				err = fmt.Errorf("failed to write report: %w", err)
+			}
+		}
+	}
+	return err
+}
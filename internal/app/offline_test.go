@@ -0,0 +1,111 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/sgaunet/awslogcheck/internal/configapp"
+	"github.com/sgaunet/awslogcheck/internal/logsource"
+)
+
+func TestCheckSources(t *testing.T) {
+	dir := t.TempDir()
+	captured := filepath.Join(dir, "captured.jsonl")
+	content := `{"timestamp":1760770800000,"logStreamName":"api",` +
+		`"message":"{\"log\":\"ERROR: payment gateway timeout\"}"}` + "\n" +
+		`{"timestamp":1760770801000,"logStreamName":"api","message":"{\"log\":\"INFO: order created\"}"}` + "\n"
+	if err := os.WriteFile(captured, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		stdin       string
+		withFile    bool
+		printed     int
+		expected    []string
+		notExpected []string
+	}{
+		{
+			name:        "file",
+			withFile:    true,
+			printed:     1,
+			expected:    []string{"<b>Parse stream</b> :api", "ERROR: payment gateway timeout"},
+			notExpected: []string{"INFO:", "<h1>"},
+		},
+		{
+			name:     "stdin and file",
+			stdin:    "INFO: starting\nWARN: disk almost full\n",
+			withFile: true,
+			printed:  2,
+			expected: []string{
+				"<h1>Source: " + captured + "</h1>",
+				"<h1>Source: stdin</h1>",
+				"<b>Parse stream</b> :stdin",
+				"WARN: disk almost full",
+			},
+			notExpected: []string{"INFO:"},
+		},
+		{name: "nothing to report", stdin: "INFO: starting\n", notExpected: []string{"<h1>", "INFO:"}},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := New(context.Background(), configapp.AppConfig{}, aws.Config{}, 3600, logger)
+			app.rules = ruleSet{ignore: mustCompileRules("^INFO:")}
+			var sources []logsource.Source
+			if tt.withFile {
+				source, err := app.NewSource(captured, "")
+				if err != nil {
+					t.Fatalf("NewSource returned error: %v", err)
+				}
+				sources = append(sources, source)
+			}
+			if tt.stdin != "" {
+				source, err := logsource.New("-", logsource.Options{Stdin: strings.NewReader(tt.stdin)})
+				if err != nil {
+					t.Fatalf("New returned error: %v", err)
+				}
+				sources = append(sources, source)
+			}
+
+			var report bytes.Buffer
+			printed, err := app.CheckSources(context.Background(), sources, &report)
+			if err != nil {
+				t.Fatalf("CheckSources returned error: %v", err)
+			}
+			for _, expected := range tt.expected {
+				if !strings.Contains(report.String(), expected) {
+					t.Errorf("Expected %q in the report:\n%s", expected, report.String())
+				}
+			}
+			for _, notExpected := range tt.notExpected {
+				if strings.Contains(report.String(), notExpected) {
+					t.Errorf("Unexpected %q in the report:\n%s", notExpected, report.String())
+				}
+			}
+			if printed != tt.printed {
+				t.Errorf("Expected %d lines printed, got %d", tt.printed, printed)
+			}
+		})
+	}
+}
+
+func TestCheckSourcesReadError(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	app := New(context.Background(), configapp.AppConfig{}, aws.Config{}, 3600, logger)
+	source, err := app.NewSource(filepath.Join(t.TempDir(), "missing.jsonl"), "")
+	if err != nil {
+		t.Fatalf("NewSource returned error: %v", err)
+	}
+	if _, err := app.CheckSources(context.Background(), []logsource.Source{source}, io.Discard); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
@@ -0,0 +1,223 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+// maxRecordedPageBytes is the longest line of a recording: a page of FilterLogEvents is 1 MB
+// at most, and its messages are escaped.
+const maxRecordedPageBytes = 8 << 20
+
+// recordedPage is a page of FilterLogEvents in a recording, one per line. The events have the
+// fields of aws logs filter-log-events, so that a recording is also an input of "check".
+type recordedPage struct {
+	Target        string          `json:"target,omitempty"`
+	LogGroupName  string          `json:"logGroupName"`
+	StartTime     int64           `json:"startTime"`
+	EndTime       int64           `json:"endTime"`
+	FilterPattern string          `json:"filterPattern,omitempty"`
+	Token         string          `json:"token,omitempty"` // NextToken of the request
+	Events        []recordedEvent `json:"events"`
+	NextToken     string          `json:"nextToken,omitempty"`
+}
+
+// recordedEvent is an event of a recorded page.
+type recordedEvent struct {
+	EventID       string `json:"eventId,omitempty"`
+	IngestionTime int64  `json:"ingestionTime,omitempty"`
+	LogStreamName string `json:"logStreamName"`
+	Message       string `json:"message"`
+	Timestamp     int64  `json:"timestamp"`
+}
+
+// recordedLogGroup is a log group of a recording, with the window it was recorded for.
+type recordedLogGroup struct {
+	target    string
+	groupName string
+	startTime int64
+	endTime   int64
+}
+
+// pageKey identifies the request of a page.
+type pageKey struct {
+	target    string
+	groupName string
+	token     string
+}
+
+// recordingClient writes the pages returned by client to w.
+type recordingClient struct {
+	client  CloudWatchLogsFilterClient
+	target  string
+	encoder *json.Encoder
+	mu      sync.Mutex
+	pages   int
+}
+
+// FilterLogEvents calls the client, and records the page returned.
+func (c *recordingClient) FilterLogEvents(ctx context.Context, params *cloudwatchlogs.FilterLogEventsInput,
+	optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.FilterLogEventsOutput, error) {
+	output, err := c.client.FilterLogEvents(ctx, params, optFns...)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // wrapped by the caller, once retries are exhausted
+	}
+	page := recordedPage{
+		Target:        c.target,
+		LogGroupName:  aws.ToString(params.LogGroupName),
+		StartTime:     aws.ToInt64(params.StartTime),
+		EndTime:       aws.ToInt64(params.EndTime),
+		FilterPattern: aws.ToString(params.FilterPattern),
+		Token:         aws.ToString(params.NextToken),
+		Events:        make([]recordedEvent, 0, len(output.Events)),
+		NextToken:     aws.ToString(output.NextToken),
+	}
+	for _, event := range output.Events {
+		page.Events = append(page.Events, recordedEvent{
+			EventID:       aws.ToString(event.EventId),
+			IngestionTime: aws.ToInt64(event.IngestionTime),
+			LogStreamName: aws.ToString(event.LogStreamName),
+			Message:       aws.ToString(event.Message),
+			Timestamp:     aws.ToInt64(event.Timestamp),
+		})
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.encoder.Encode(page); err != nil {
+		return nil, fmt.Errorf("failed to record page: %w", err)
+	}
+	c.pages++
+	return output, nil
+}
+
+// Record writes the pages of FilterLogEvents of the log groups of the configuration, between
+// minTimeStampInMs and maxTimeStampInMs, to w. The report is not computed.
+func (a *App) Record(ctx context.Context, w io.Writer, minTimeStampInMs, maxTimeStampInMs int64) error {
+	checks, errs := a.resolveTargets(ctx)
+	for _, err := range errs {
+		a.appLog.Error(err.Error())
+	}
+	if len(checks) == 0 {
+		return errors.Join(errs...)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	for _, check := range checks {
+		recorder := &recordingClient{client: check.client, target: check.target, encoder: encoder}
+		input := a.buildFilterLogEventsInput(check.groupName, minTimeStampInMs, maxTimeStampInMs)
+		// Sequential, so that the pages are replayed in the same order
+		_, err := a.fetchPages(ctx, recorder, input, func([]types.FilteredLogEvent) error { return nil })
+		if err != nil {
+			a.appLog.Error("Failed to record log group", slog.String("target", check.target),
+				slog.String("groupName", check.groupName), slog.String("error", err.Error()))
+			errs = append(errs, err)
+			continue
+		}
+		a.appLog.Info("Log group recorded", slog.String("target", check.target),
+			slog.String("groupName", check.groupName), slog.Int("pages", recorder.pages))
+	}
+	return errors.Join(errs...)
+}
+
+// replayClient answers FilterLogEvents with the pages of a recording.
+type replayClient struct {
+	target string
+	pages  map[pageKey]recordedPage
+}
+
+// FilterLogEvents returns the recorded page of the log group and token of params.
+func (c *replayClient) FilterLogEvents(_ context.Context, params *cloudwatchlogs.FilterLogEventsInput,
+	_ ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.FilterLogEventsOutput, error) {
+	key := pageKey{target: c.target, groupName: aws.ToString(params.LogGroupName), token: aws.ToString(params.NextToken)}
+	page, ok := c.pages[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: log group %s, token %q", ErrPageNotRecorded, key.groupName, key.token)
+	}
+	output := &cloudwatchlogs.FilterLogEventsOutput{Events: make([]types.FilteredLogEvent, 0, len(page.Events))}
+	if page.NextToken != "" {
+		output.NextToken = aws.String(page.NextToken)
+	}
+	for _, event := range page.Events {
+		filtered := types.FilteredLogEvent{
+			LogStreamName: aws.String(event.LogStreamName),
+			Message:       aws.String(event.Message),
+			Timestamp:     aws.Int64(event.Timestamp),
+		}
+		if event.EventID != "" {
+			filtered.EventId = aws.String(event.EventID)
+		}
+		if event.IngestionTime != 0 {
+			filtered.IngestionTime = aws.Int64(event.IngestionTime)
+		}
+		output.Events = append(output.Events, filtered)
+	}
+	return output, nil
+}
+
+// readRecording returns the pages of a recording, and its log groups in the recorded order.
+func readRecording(r io.Reader) (map[pageKey]recordedPage, []recordedLogGroup, error) {
+	pages := make(map[pageKey]recordedPage)
+	var groups []recordedLogGroup
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxRecordedPageBytes)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var page recordedPage
+		if err := json.Unmarshal(scanner.Bytes(), &page); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode recorded page at line %d: %w", lineNumber, err)
+		}
+		if page.Token == "" {
+			groups = append(groups, recordedLogGroup{
+				target:    page.Target,
+				groupName: page.LogGroupName,
+				startTime: page.StartTime,
+				endTime:   page.EndTime,
+			})
+		}
+		pages[pageKey{target: page.Target, groupName: page.LogGroupName, token: page.Token}] = page
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read recording: %w", err)
+	}
+	return pages, groups, nil
+}
+
+// Replay checks the log groups of a recording, as recorded, and returns the number of lines
+// reported. The report is written to w, or sent by mail if w is nil.
+func (a *App) Replay(ctx context.Context, r io.Reader, w io.Writer) (int, error) {
+	pages, groups, err := readRecording(r)
+	if err != nil {
+		return 0, err
+	}
+	return a.reportTo(ctx, w, func(chLogLines chan<- string) (int, []error) {
+		var errs []error
+		cptLinePrinted := 0
+		for _, group := range groups {
+			check := logGroupCheck{target: group.target, groupName: group.groupName}
+			chGroupLines, closeGroupLines := withHeader(check.header(len(groups)), chLogLines)
+			// The pages are chained by their tokens: the time slices of concurrent fetches are not recorded
+			printed, err := a.parseAllEventsWithWorkers(ctx, &replayClient{target: group.target, pages: pages}, 1,
+				group.groupName, group.startTime, group.endTime, chGroupLines)
+			closeGroupLines()
+			cptLinePrinted += printed
+			if err != nil {
+				a.appLog.Error("Failed to replay log group", slog.String("target", group.target),
+					slog.String("groupName", group.groupName), slog.String("error", err.Error()))
+				errs = append(errs, err)
+			}
+		}
+		return cptLinePrinted, errs
+	})
+}
@@ -0,0 +1,137 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/sgaunet/awslogcheck/internal/configapp"
+)
+
+var updateGolden = flag.Bool("update", false, "Update the golden files of testdata/replay")
+
+// newReplayApp returns the app of the golden-file tests.
+func newReplayApp() *App {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	// Replays are fetched sequentially, whatever the configuration
+	cfg := configapp.AppConfig{ContainerNameToIgnore: []string{"sidecar"}, Fetch: configapp.FetchConfig{Workers: 4}}
+	app := New(context.Background(), cfg, aws.Config{}, 3600, logger)
+	app.rules = ruleSet{ignore: mustCompileRules("^DEBUG:", "^INFO:")}
+	return app
+}
+
+// TestReplayGolden replays the recordings of testdata/replay, and compares the reports to
+// their golden files. Run with -update to write them.
+func TestReplayGolden(t *testing.T) {
+	tests := []struct {
+		recording string
+		printed   int
+	}{
+		{recording: "pages", printed: 2},
+		{recording: "targets", printed: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.recording, func(t *testing.T) {
+			recording, err := os.Open(filepath.Join("testdata", "replay", tt.recording+".jsonl"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer recording.Close()
+
+			var report bytes.Buffer
+			printed, err := newReplayApp().Replay(context.Background(), recording, &report)
+			if err != nil {
+				t.Fatalf("Replay returned error: %v", err)
+			}
+			if printed != tt.printed {
+				t.Errorf("Expected %d lines printed, got %d", tt.printed, printed)
+			}
+
+			golden := filepath.Join("testdata", "replay", tt.recording+".golden")
+			if *updateGolden {
+				if err := os.WriteFile(golden, report.Bytes(), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			expected, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.String() != string(expected) {
+				t.Errorf("Report differs from %s:\n%s", golden, report.String())
+			}
+		})
+	}
+}
+
+// TestRecordingRoundTrip records the pages of a replayed recording, which must be the same.
+func TestRecordingRoundTrip(t *testing.T) {
+	content, err := os.ReadFile(filepath.Join("testdata", "replay", "pages.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages, groups, err := readRecording(bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("readRecording returned error: %v", err)
+	}
+	if len(groups) != 1 {
+		t.Fatalf("Expected 1 log group, got %v", groups)
+	}
+
+	app := newReplayApp()
+	var recorded bytes.Buffer
+	encoder := json.NewEncoder(&recorded)
+	encoder.SetEscapeHTML(false)
+	recorder := &recordingClient{client: &replayClient{pages: pages}, encoder: encoder}
+	input := app.buildFilterLogEventsInput(groups[0].groupName, groups[0].startTime, groups[0].endTime)
+	eventCount := 0
+	pageCount, err := app.fetchPages(context.Background(), recorder, input, func(events []types.FilteredLogEvent) error {
+		eventCount += len(events)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("fetchPages returned error: %v", err)
+	}
+	if pageCount != 2 || eventCount != 7 {
+		t.Errorf("Expected 2 pages and 7 events, got %d and %d", pageCount, eventCount)
+	}
+	if recorded.String() != string(content) {
+		t.Errorf("Recording differs:\n%s", recorded.String())
+	}
+}
+
+func TestReplayErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		recording string
+		expected  error
+	}{
+		{
+			name:      "missing page",
+			recording: `{"logGroupName":"app","startTime":1,"endTime":2,"events":[],"nextToken":"next"}` + "\n",
+			expected:  ErrPageNotRecorded,
+		},
+		{name: "invalid recording", recording: "not json\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newReplayApp().Replay(context.Background(), strings.NewReader(tt.recording), io.Discard)
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			if tt.expected != nil && !errors.Is(err, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
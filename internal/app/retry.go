@@ -0,0 +1,119 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"golang.org/x/time/rate"
+)
+
+// Defaults of the retries of the AWS API calls.
+const (
+	defaultRetryMaxAttempts = 8
+	defaultRetryBaseDelay   = 200   // Milliseconds
+	defaultRetryMaxDelay    = 20000 // Milliseconds
+)
+
+// Classification of the errors: the ones of the AWS SDK, and the transient errors of CloudWatch Logs.
+var (
+	retryables = retry.IsErrorRetryables(append([]retry.IsErrorRetryable{
+		retry.RetryableHTTPStatusCode{Codes: retry.DefaultRetryableHTTPStatusCodes},
+		retry.RetryableErrorCode{Codes: map[string]struct{}{
+			"ServiceUnavailableException": {},
+			"InternalFailure":             {},
+		}},
+	}, retry.DefaultRetryables...))
+	throttles = retry.IsErrorThrottles(retry.DefaultThrottles)
+)
+
+// slowDown halves the rate of limiter, down to one call per second.
+func slowDown(limiter *rate.Limiter) rate.Limit {
+	limit := max(limiter.Limit()/2, 1)
+	limiter.SetLimit(limit)
+	limiter.SetBurst(int(limit))
+	return limit
+}
+
+// speedUp raises the rate of limiter by one call per second, up to maxLimit.
+func speedUp(limiter *rate.Limiter, maxLimit rate.Limit) {
+	if limiter.Limit() >= maxLimit {
+		return
+	}
+	limit := min(limiter.Limit()+1, maxLimit)
+	limiter.SetLimit(limit)
+	limiter.SetBurst(int(limit))
+}
+
+// isRetryable checks if a failed call can be retried, and if the API is throttling.
+func isRetryable(err error) (bool, bool) {
+	throttled := throttles.IsErrorThrottle(err) == aws.TrueTernary
+	return throttled || retryables.IsErrorRetryable(err) == aws.TrueTernary, throttled
+}
+
+// withRetry calls fn, waiting for limiter before each attempt, and retries it with a
+// jittered exponential backoff while it fails with a transient error. The rate of limiter
+// is lowered when the API is throttling, and raised back up to maxLimit on success.
+func (a *App) withRetry(ctx context.Context, operation string, limiter *rate.Limiter, maxLimit rate.Limit,
+	fn func() error) error {
+	maxAttempts := a.cfg.Retry.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultRetryMaxAttempts
+	}
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("context cancelled: %w", err)
+		}
+		if err := limiter.Wait(ctx); err != nil {
+			return fmt.Errorf("rate limit wait error: %w", err)
+		}
+		err := fn()
+		if err == nil {
+			speedUp(limiter, maxLimit)
+			return nil
+		}
+		retryable, throttled := isRetryable(err)
+		if !retryable || attempt >= maxAttempts || ctx.Err() != nil {
+			return err
+		}
+		if throttled {
+			limit := slowDown(limiter)
+			a.appLog.Warn("API throttling, rate lowered",
+				slog.String("operation", operation), slog.Float64("callsPerSecond", float64(limit)))
+		}
+		delay := a.backoff(attempt)
+		a.appLog.Warn("API call failed, retrying",
+			slog.String("operation", operation),
+			slog.Int("attempt", attempt),
+			slog.Duration("delay", delay),
+			slog.String("error", err.Error()))
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("context cancelled: %w", ctx.Err())
+		case <-time.After(delay):
+		}
+	}
+}
+
+// backoff returns the delay before the next attempt: a random duration up to an
+// exponentially growing maximum (full jitter).
+func (a *App) backoff(attempt int) time.Duration {
+	base := a.cfg.Retry.BaseDelay
+	if base <= 0 {
+		base = defaultRetryBaseDelay
+	}
+	maxDelay := a.cfg.Retry.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = defaultRetryMaxDelay
+	}
+	ceiling := time.Duration(maxDelay) * time.Millisecond
+	if shift := attempt - 1; shift < 30 {
+		ceiling = min(ceiling, time.Duration(base)*time.Millisecond<<shift)
+	}
+	// #nosec G404 - jitter does not need a secure random number
+	return time.Duration(rand.Int64N(int64(ceiling) + 1))
+}
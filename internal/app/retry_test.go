@@ -0,0 +1,130 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/smithy-go"
+	"github.com/sgaunet/awslogcheck/internal/configapp"
+	"golang.org/x/time/rate"
+)
+
+// flakyFilterClient fails the calls listed in failures, then delegates to client.
+type flakyFilterClient struct {
+	client   CloudWatchLogsFilterClient
+	failures map[int]error // By call number, starting at 1
+	calls    int
+	tokens   []string // NextToken of every call
+}
+
+func (f *flakyFilterClient) FilterLogEvents(ctx context.Context, params *cloudwatchlogs.FilterLogEventsInput,
+	optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.FilterLogEventsOutput, error) {
+	f.calls++
+	f.tokens = append(f.tokens, aws.ToString(params.NextToken))
+	if err, ok := f.failures[f.calls]; ok {
+		return nil, err
+	}
+	return f.client.FilterLogEvents(ctx, params, optFns...)
+}
+
+func newRetryApp() *App {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := configapp.AppConfig{Retry: configapp.RetryConfig{MaxAttempts: 3, BaseDelay: 1, MaxDelay: 5}}
+	return New(context.Background(), cfg, aws.Config{}, 3600, logger)
+}
+
+func TestFetchResumesAfterThrottling(t *testing.T) {
+	app := newRetryApp()
+	now := time.Now().Unix() * 1000
+	var events []types.FilteredLogEvent
+	for range 6 {
+		events = append(events, createLogEvent(now-1000, "stream-1", "pod", "app:latest", "app", "ERROR"))
+	}
+	throttled := &smithy.GenericAPIError{Code: "ThrottlingException", Message: "Rate exceeded"}
+	client := &flakyFilterClient{
+		client:   &mockRangeClient{events: events, pageSize: 2},
+		failures: map[int]error{2: throttled, 3: throttled},
+	}
+
+	printed, err := app.parseAllEventsWithFilterClient(context.Background(), client, "test-group",
+		now-3600000, now, make(chan string, 1000))
+	if err != nil {
+		t.Fatalf("parseAllEventsWithFilterClient returned error: %v", err)
+	}
+	if printed != 6 {
+		t.Errorf("Expected 6 printed lines, got %d", printed)
+	}
+	// The failed page is fetched again with its token
+	expectedTokens := []string{"", "2", "2", "2", "4"}
+	if len(client.tokens) != len(expectedTokens) {
+		t.Fatalf("Expected tokens %v, got %v", expectedTokens, client.tokens)
+	}
+	for i, token := range expectedTokens {
+		if client.tokens[i] != token {
+			t.Errorf("Call %d: expected token %q, got %q", i+1, token, client.tokens[i])
+		}
+	}
+	if limit := app.eventsRateLimit.Limit(); limit >= maxEventsAPICallPerSecond {
+		t.Errorf("The rate should have been lowered, got %v", limit)
+	}
+}
+
+func TestFetchErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		err           error
+		expectedCalls int
+	}{
+		{"not retryable", &smithy.GenericAPIError{Code: "ResourceNotFoundException"}, 1},
+		{"attempts exhausted", &smithy.GenericAPIError{Code: "ServiceUnavailableException"}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newRetryApp()
+			client := &flakyFilterClient{
+				client:   &mockRangeClient{},
+				failures: map[int]error{1: tt.err, 2: tt.err, 3: tt.err},
+			}
+			_, err := app.parseAllEventsWithFilterClient(context.Background(), client, "test-group",
+				0, 3600000, make(chan string, 1000))
+			if !errors.Is(err, tt.err) {
+				t.Errorf("Expected %v, got %v", tt.err, err)
+			}
+			if client.calls != tt.expectedCalls {
+				t.Errorf("Expected %d calls, got %d", tt.expectedCalls, client.calls)
+			}
+		})
+	}
+}
+
+func TestRateLimiterAdaptation(t *testing.T) {
+	limiter := rate.NewLimiter(rate.Limit(25), 25)
+	for range 10 {
+		slowDown(limiter)
+	}
+	if limiter.Limit() != 1 {
+		t.Errorf("The rate should not go below 1, got %v", limiter.Limit())
+	}
+	for range 50 {
+		speedUp(limiter, 25)
+	}
+	if limiter.Limit() != 25 || limiter.Burst() != 25 {
+		t.Errorf("The rate should be back to 25, got %v", limiter.Limit())
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	app := newRetryApp()
+	for attempt := 1; attempt < 100; attempt++ {
+		if delay := app.backoff(attempt); delay < 0 || delay > 5*time.Millisecond {
+			t.Fatalf("Attempt %d: delay %v out of bounds", attempt, delay)
+		}
+	}
+}
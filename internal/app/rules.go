@@ -0,0 +1,244 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+
+	"github.com/sgaunet/awslogcheck/internal/configapp"
+	"github.com/sgaunet/awslogcheck/internal/rules"
+)
+
+// rule is a compiled rule.
+type rule struct {
+	re     *regexp.Regexp
+	source rules.Rule
+	hits   int // Lines matched during the current run
+}
+
+// ruleSet holds the compiled rules by category.
+type ruleSet struct {
+	ignore []*rule // Matching lines are not reported
+	alert  []*rule // Matching lines are always reported as critical
+	// Categories of the Debian logcheck layout
+	violations       []*rule
+	violationsIgnore []*rule
+	cracking         []*rule
+	crackingIgnore   []*rule
+}
+
+// LoadRules loads regexp rules that will be used to ignore events (log).
+// The rules of the remote sources are read from their local copies, see SyncRuleSources.
+func (a *App) LoadRules() error {
+	dirs, err := a.rulesDirs()
+	if err != nil {
+		return err
+	}
+	for _, rulesDir := range dirs {
+		if err := a.loadRulesDir(rulesDir); err != nil {
+			return err
+		}
+	}
+	a.appLog.Debug("Rules loaded",
+		slog.Int("ignore", len(a.rules.ignore)),
+		slog.Int("alert", len(a.rules.alert)),
+		slog.Int("violations", len(a.rules.violations)),
+		slog.Int("cracking", len(a.rules.cracking)))
+	return nil
+}
+
+// rulesDirs returns the local rules directory followed by the directories of the remote sources.
+// The local directory is optional when remote sources are configured.
+func (a *App) rulesDirs() ([]string, error) {
+	var dirs []string
+	rulesDir, err := a.cfg.GetRulesDir()
+	switch {
+	case errors.Is(err, configapp.ErrRulesDirNotFound) && a.cfg.HasRuleSources():
+	case err != nil:
+		return nil, fmt.Errorf("failed to get rules directory: %w", err)
+	case rulesDir == "":
+		return nil, fmt.Errorf("%w", ErrNoRulesFolder)
+	default:
+		if _, err := os.Stat(rulesDir); err != nil {
+			return nil, fmt.Errorf("failed to stat rules directory: %w", err)
+		}
+		dirs = append(dirs, rulesDir)
+	}
+
+	sources, err := a.ruleSources()
+	if err != nil {
+		return nil, err
+	}
+	for _, source := range sources {
+		if !dirExists(source.Dir()) {
+			return nil, fmt.Errorf("%w: %s", ErrRuleSourceNotSynced, source.URL())
+		}
+		dirs = append(dirs, source.Dir())
+	}
+	return dirs, nil
+}
+
+// loadRulesDir loads the rules of a directory.
+func (a *App) loadRulesDir(rulesDir string) error {
+	if a.cfg.IsLogcheckFormat() {
+		return a.loadLogcheckRules(rulesDir)
+	}
+	loaded, problems, err := rules.LoadDir(rulesDir)
+	if err != nil {
+		return fmt.Errorf("failed to load rules: %w", err)
+	}
+	a.skipProblems(problems)
+	a.addRules(loaded)
+	return nil
+}
+
+// ValidateRules returns an error if some of the loaded rules or of the ignore selectors are
+// incorrect.
+func (a *App) ValidateRules() error {
+	var errs []error
+	if a.skippedRules > 0 {
+		errs = append(errs, fmt.Errorf("%w: %d rule(s) are incorrect", ErrInvalidRules, a.skippedRules))
+	}
+	if a.skippedSelectors > 0 {
+		errs = append(errs, fmt.Errorf("%w: %d ignore selector(s) do not parse", ErrInvalidSelector, a.skippedSelectors))
+	}
+	return errors.Join(errs...)
+}
+
+// loadLogcheckRules loads a rules directory using the Debian logcheck layout.
+func (a *App) loadLogcheckRules(rulesDir string) error {
+	loaded, err := rules.LoadLogcheckDir(rulesDir, a.cfg.LogcheckLevel)
+	if err != nil {
+		return fmt.Errorf("failed to load logcheck rules: %w", err)
+	}
+	a.skipProblems(loaded.Problems)
+	a.addRules(loaded.Ignore)
+	a.rules.violations = append(a.rules.violations, a.compileRules(loaded.Violations)...)
+	a.rules.violationsIgnore = append(a.rules.violationsIgnore, a.compileRules(loaded.ViolationsIgnore)...)
+	a.rules.cracking = append(a.rules.cracking, a.compileRules(loaded.Cracking)...)
+	a.rules.crackingIgnore = append(a.rules.crackingIgnore, a.compileRules(loaded.CrackingIgnore)...)
+	return nil
+}
+
+// skipProblems logs the rules that could not be loaded, and counts the incorrect ones as
+// skipped. Valid ERE rules without RE2 equivalent (backreferences...) are only logged.
+func (a *App) skipProblems(problems []rules.Problem) {
+	for _, problem := range problems {
+		a.appLog.Warn("Rule skipped", slog.String("rule", problem.String()))
+		if !errors.Is(problem.Err, rules.ErrUntranslatable) {
+			a.skippedRules++
+		}
+	}
+}
+
+// dirExists checks if dir is an existing directory.
+func dirExists(dir string) bool {
+	info, err := os.Stat(dir)
+	return err == nil && info.IsDir()
+}
+
+// addRules compiles loaded and dispatches them between ignore and alert rules.
+func (a *App) addRules(loaded []rules.Rule) {
+	for _, compiled := range a.compileRules(loaded) {
+		if compiled.source.Alert {
+			a.rules.alert = append(a.rules.alert, compiled)
+		} else {
+			a.rules.ignore = append(a.rules.ignore, compiled)
+		}
+	}
+}
+
+// compileRules compiles the loaded rules, incorrect ones are logged and skipped.
+func (a *App) compileRules(loaded []rules.Rule) []*rule {
+	compiled := make([]*rule, 0, len(loaded))
+	for _, source := range loaded {
+		if source.IsField() {
+			compiled = append(compiled, &rule{source: source})
+			continue
+		}
+		re, err := regexp.Compile(source.Pattern)
+		if err != nil {
+			a.appLog.Error("rule is incorrect",
+				slog.String("rule", source.Pattern),
+				slog.String("file", source.File),
+				slog.Int("line", source.Line),
+				slog.String("error", err.Error()))
+			a.skippedRules++
+			continue
+		}
+		compiled = append(compiled, &rule{re: re, source: source})
+	}
+	return compiled
+}
+
+// logMessage is a line to classify. It is decoded as JSON only if a field rule is tested.
+type logMessage struct {
+	line    string
+	fields  map[string]any // nil if the line is not a JSON object
+	decoded bool
+}
+
+func (m *logMessage) decodedFields() map[string]any {
+	if !m.decoded {
+		m.fields = rules.DecodeFields(m.line)
+		m.decoded = true
+	}
+	return m.fields
+}
+
+// classifyLine returns the report section of line, sectionIgnored if it must not be reported.
+// Alert rules win over every other rule, then the logcheck cracking.d and violations.d
+// rules are checked before the ignore rules, as logcheck does.
+func (a *App) classifyLine(line string) reportSection {
+	msg := &logMessage{line: line}
+	section := a.reportedSection(msg)
+	// The ignore rules are matched even when another rule reports the line, so that their
+	// hits, and the rules without hits of the statistics, don't depend on the other rules
+	ignored := a.isLineMatchWithOneRule(msg, a.rules.ignore)
+	switch {
+	case section != sectionSystem:
+		return section
+	case ignored:
+		return sectionIgnored
+	}
+	a.appLog.Debug("Line matches no rules", slog.String("line", line))
+	return sectionSystem
+}
+
+// reportedSection returns the section of msg according to the rules reporting lines whatever
+// the ignore rules, sectionSystem if none of them matches.
+func (a *App) reportedSection(msg *logMessage) reportSection {
+	if a.isLineMatchWithOneRule(msg, a.rules.alert) {
+		return sectionCritical
+	}
+	if a.isLineMatchWithOneRule(msg, a.rules.cracking) &&
+		!a.isLineMatchWithOneRule(msg, a.rules.crackingIgnore) {
+		return sectionAlerts
+	}
+	if a.isLineMatchWithOneRule(msg, a.rules.violations) &&
+		!a.isLineMatchWithOneRule(msg, a.rules.violationsIgnore) {
+		return sectionSecurity
+	}
+	return sectionSystem
+}
+
+// isLineMatchWithOneRule checks msg against candidates. Field rules only match
+// JSON messages, regexps are matched against the raw line.
+func (a *App) isLineMatchWithOneRule(msg *logMessage, candidates []*rule) bool {
+	for _, r := range candidates {
+		var matched bool
+		if r.source.IsField() {
+			matched = r.source.MatchFields(msg.decodedFields())
+		} else {
+			matched = r.re.MatchString(msg.line)
+		}
+		if matched {
+			r.hits++
+			a.appLog.Debug("Rule match", slog.String("rule", r.source.Raw), slog.String("line", msg.line))
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/sgaunet/awslogcheck/internal/rulesource"
+)
+
+// ruleSources returns the remote rule sources of the configuration.
+func (a *App) ruleSources() ([]rulesource.Source, error) {
+	opts := rulesource.Options{
+		CacheDir: a.cfg.GetRulesCacheDir(),
+		S3:       a.newS3Client(a.awscfg),
+	}
+	sources := make([]rulesource.Source, 0, len(a.cfg.RuleSources))
+	for _, rawURL := range a.cfg.RuleSources {
+		source, err := rulesource.New(rawURL, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure rule source: %w", err)
+		}
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
+
+// SyncRuleSources writes the changes of the remote rule sources in staging
+// copies, and reports whether one of them changed. The staging copies are used
+// by LoadRules, and replace the local copies once the rules are valid, see
+// LoadSyncedRules. If a source can't be reached, its previous copy is used;
+// it's an error only if there is none.
+func (a *App) SyncRuleSources(ctx context.Context) (bool, error) {
+	sources, err := a.ruleSources()
+	if err != nil {
+		return false, err
+	}
+	changed := false
+	for _, source := range sources {
+		updated, err := source.Sync(ctx)
+		if err != nil {
+			if !dirExists(source.Dir()) {
+				a.DiscardRuleSources()
+				return false, fmt.Errorf("failed to sync rule source %s: %w", source.URL(), err)
+			}
+			a.appLog.Warn("Cannot sync rule source, using the cached rules",
+				slog.String("source", source.URL()),
+				slog.String("error", err.Error()))
+			continue
+		}
+		a.appLog.Debug("Rule source synced",
+			slog.String("source", source.URL()),
+			slog.Bool("changed", updated))
+		changed = changed || updated
+	}
+	return changed, nil
+}
+
+// LoadSyncedRules loads the rules, from the staging copies of the rule sources
+// if they changed, and validates them. The staging copies replace the local
+// copies only if the rules are valid: otherwise they are discarded, and
+// downloaded again at the next sync.
+func (a *App) LoadSyncedRules() error {
+	err := a.LoadRules()
+	if err == nil {
+		err = a.ValidateRules()
+	}
+	if err != nil {
+		a.DiscardRuleSources()
+		return err
+	}
+	return a.CommitRuleSources()
+}
+
+// LoadStartupRules loads the rules like LoadSyncedRules, but incorrect rules and
+// ignore selectors are only logged and skipped: the program starts with the
+// valid ones. The staging copies of the rule sources are still discarded.
+func (a *App) LoadStartupRules() error {
+	err := a.LoadSyncedRules()
+	if !errors.Is(err, ErrInvalidRules) && !errors.Is(err, ErrInvalidSelector) {
+		return err
+	}
+	a.appLog.Warn("Incorrect rules skipped", slog.String("error", err.Error()))
+	return nil
+}
+
+// CommitRuleSources replaces the local copies of the rule sources with their staging copies.
+func (a *App) CommitRuleSources() error {
+	sources, err := a.ruleSources()
+	if err != nil {
+		return err
+	}
+	for _, source := range sources {
+		if err := source.Commit(); err != nil {
+			return fmt.Errorf("failed to commit rule source %s: %w", source.URL(), err)
+		}
+	}
+	return nil
+}
+
+// DiscardRuleSources removes the staging copies of the rule sources.
+func (a *App) DiscardRuleSources() {
+	sources, err := a.ruleSources()
+	if err != nil {
+		return
+	}
+	for _, source := range sources {
+		if err := source.Discard(); err != nil {
+			a.appLog.Warn("Cannot discard rule source", slog.String("source", source.URL()),
+				slog.String("error", err.Error()))
+		}
+	}
+}
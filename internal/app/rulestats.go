@@ -0,0 +1,241 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	defaultRuleStatsHistoryDays = 90
+	ruleStatsDayFormat          = "2006-01-02"
+	ruleStatsFileMode           = 0o600
+)
+
+// ruleStatsHistory is the content of the rule statistics file: hits per rule and per day.
+type ruleStatsHistory struct {
+	Rules []ruleStatsEntry `json:"rules"`
+}
+
+type ruleStatsEntry struct {
+	File    string         `json:"file"`
+	Pattern string         `json:"pattern"`
+	Hits    map[string]int `json:"hits"` // Hits per day (YYYY-MM-DD)
+}
+
+// ruleKey identifies a rule across runs, line numbers change too often to be used.
+type ruleKey struct {
+	file    string
+	pattern string
+}
+
+func (r *rule) key() ruleKey {
+	return ruleKey{file: r.source.File, pattern: r.source.Raw}
+}
+
+// allRules returns the rules of every category.
+func (s *ruleSet) allRules() []*rule {
+	all := make([]*rule, 0, len(s.alert)+len(s.ignore))
+	for _, category := range [][]*rule{
+		s.alert, s.cracking, s.crackingIgnore, s.violations, s.violationsIgnore, s.ignore,
+	} {
+		all = append(all, category...)
+	}
+	return all
+}
+
+// outputRuleStats appends the hits of the current run to the report.
+func (a *App) outputRuleStats(chLogLines chan<- string) {
+	all := a.rules.allRules()
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].hits > all[j].hits
+	})
+	chLogLines <- "<h2>Rule statistics</h2>\n<table>\n<tr><th>Hits</th><th>Rule</th><th>File</th></tr>\n"
+	for _, r := range all {
+		chLogLines <- fmt.Sprintf("<tr><td>%d</td><td>%s</td><td>%s:%d</td></tr>\n",
+			r.hits, html.EscapeString(r.source.Raw), html.EscapeString(r.source.File), r.source.Line)
+	}
+	chLogLines <- "</table>\n"
+}
+
+// saveRuleStats adds the hits of the current run to the statistics file, and resets the counters.
+func (a *App) saveRuleStats(now time.Time) error {
+	all := a.rules.allRules()
+	defer func() {
+		for _, r := range all {
+			r.hits = 0
+		}
+	}()
+	if a.cfg.RuleStats.File == "" {
+		return nil
+	}
+	history, err := readRuleStatsHistory(a.cfg.RuleStats.File)
+	if err != nil {
+		return err
+	}
+
+	hits := history.byRule()
+	day := now.UTC().Format(ruleStatsDayFormat)
+	for _, r := range all {
+		if r.hits == 0 {
+			continue
+		}
+		if hits[r.key()] == nil {
+			hits[r.key()] = map[string]int{}
+		}
+		hits[r.key()][day] += r.hits
+	}
+	history = newRuleStatsHistory(hits)
+
+	historyDays := a.cfg.RuleStats.HistoryDays
+	if historyDays <= 0 {
+		historyDays = defaultRuleStatsHistoryDays
+	}
+	history.prune(now.UTC().AddDate(0, 0, -historyDays).Format(ruleStatsDayFormat))
+	return writeRuleStatsHistory(a.cfg.RuleStats.File, history)
+}
+
+// byRule returns the hits per day of every rule of the history.
+func (h *ruleStatsHistory) byRule() map[ruleKey]map[string]int {
+	hits := make(map[ruleKey]map[string]int, len(h.Rules))
+	for _, entry := range h.Rules {
+		hits[ruleKey{file: entry.File, pattern: entry.Pattern}] = entry.Hits
+	}
+	return hits
+}
+
+// newRuleStatsHistory builds a history sorted by file and pattern, to keep the file stable.
+func newRuleStatsHistory(hits map[ruleKey]map[string]int) *ruleStatsHistory {
+	history := &ruleStatsHistory{Rules: make([]ruleStatsEntry, 0, len(hits))}
+	for key, days := range hits {
+		history.Rules = append(history.Rules, ruleStatsEntry{File: key.file, Pattern: key.pattern, Hits: days})
+	}
+	sort.Slice(history.Rules, func(i, j int) bool {
+		if history.Rules[i].File != history.Rules[j].File {
+			return history.Rules[i].File < history.Rules[j].File
+		}
+		return history.Rules[i].Pattern < history.Rules[j].Pattern
+	})
+	return history
+}
+
+// prune removes the days before oldestDay, and the rules without hits left.
+func (h *ruleStatsHistory) prune(oldestDay string) {
+	kept := h.Rules[:0]
+	for _, entry := range h.Rules {
+		for day := range entry.Hits {
+			if day < oldestDay {
+				delete(entry.Hits, day)
+			}
+		}
+		if len(entry.Hits) > 0 {
+			kept = append(kept, entry)
+		}
+	}
+	h.Rules = kept
+}
+
+func readRuleStatsHistory(filename string) (*ruleStatsHistory, error) {
+	history := &ruleStatsHistory{}
+	// #nosec G304 - filename comes from the configuration file
+	content, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return history, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rule statistics: %w", err)
+	}
+	if err := json.Unmarshal(content, history); err != nil {
+		return nil, fmt.Errorf("failed to parse rule statistics: %w", err)
+	}
+	return history, nil
+}
+
+// writeRuleStatsHistory replaces the statistics file atomically.
+func writeRuleStatsHistory(filename string, history *ruleStatsHistory) error {
+	content, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal rule statistics: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(filename), ".rulestats-*")
+	if err != nil {
+		return fmt.Errorf("failed to create rule statistics: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write rule statistics: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write rule statistics: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), ruleStatsFileMode); err != nil {
+		return fmt.Errorf("failed to write rule statistics: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		return fmt.Errorf("failed to write rule statistics: %w", err)
+	}
+	return nil
+}
+
+// WriteRuleStats prints the hits of the loaded rules over the last days,
+// followed by the rules without any hit in that period.
+func (a *App) WriteRuleStats(w io.Writer, days int, now time.Time) error {
+	if a.cfg.RuleStats.File == "" {
+		return fmt.Errorf("%w", ErrRuleStatsNotConfigured)
+	}
+	history, err := readRuleStatsHistory(a.cfg.RuleStats.File)
+	if err != nil {
+		return err
+	}
+	oldestDay := now.UTC().AddDate(0, 0, -days).Format(ruleStatsDayFormat)
+	firstDay := ""
+	hits := make(map[ruleKey]int, len(history.Rules))
+	for key, perDay := range history.byRule() {
+		for day, count := range perDay {
+			if firstDay == "" || day < firstDay {
+				firstDay = day
+			}
+			if day >= oldestDay {
+				hits[key] += count
+			}
+		}
+	}
+
+	all := a.rules.allRules()
+	sort.SliceStable(all, func(i, j int) bool {
+		return hits[all[i].key()] > hits[all[j].key()]
+	})
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(tw, "HITS\tRULE\tFILE\n")
+	var dead []*rule
+	for _, r := range all {
+		count := hits[r.key()]
+		if count == 0 {
+			dead = append(dead, r)
+			continue
+		}
+		_, _ = fmt.Fprintf(tw, "%d\t%s\t%s:%d\n", count, r.source.Raw, r.source.File, r.source.Line)
+	}
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("failed to print rule statistics: %w", err)
+	}
+
+	_, _ = fmt.Fprintf(w, "\nRules without hits in the last %d days: %d\n", days, len(dead))
+	if firstDay > oldestDay {
+		_, _ = fmt.Fprintf(w, "(statistics are only available since %s)\n", firstDay)
+	}
+	for _, r := range dead {
+		_, _ = fmt.Fprintf(w, "%s:%d: %s\n", r.source.File, r.source.Line, r.source.Raw)
+	}
+	a.appLog.Debug("Rule statistics printed", slog.Int("rules", len(all)), slog.Int("dead", len(dead)))
+	return nil
+}
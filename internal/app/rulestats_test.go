@@ -0,0 +1,103 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/sgaunet/awslogcheck/internal/configapp"
+)
+
+// TestRuleStats tests the hit counters, their history and the dead rules
+func TestRuleStats(t *testing.T) {
+	statsFile := filepath.Join(t.TempDir(), "rulestats.json")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := configapp.AppConfig{
+		RuleStats: configapp.RuleStatsConfig{File: statsFile, HistoryDays: 10},
+	}
+	app := New(context.Background(), cfg, aws.Config{}, 3600, logger)
+	app.rules = ruleSet{
+		ignore: mustCompileRules("^INFO:", "^DEBUG:", "never matches"),
+		alert:  mustCompileRules("panic:"),
+	}
+
+	now := time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)
+	// A run 20 days ago, out of the history window
+	app.classifyLine("never matches")
+	if err := app.saveRuleStats(now.AddDate(0, 0, -20)); err != nil {
+		t.Fatalf("saveRuleStats returned error: %v", err)
+	}
+
+	for _, line := range []string{"INFO: a", "INFO: b", "DEBUG: c", "panic: d", "ERROR: e"} {
+		app.classifyLine(line)
+	}
+	if app.rules.ignore[0].hits != 2 || app.rules.alert[0].hits != 1 {
+		t.Errorf("Unexpected hits: %d %d", app.rules.ignore[0].hits, app.rules.alert[0].hits)
+	}
+
+	chLogLines := make(chan string, 100)
+	app.outputRuleStats(chLogLines)
+	close(chLogLines)
+	var report strings.Builder
+	for line := range chLogLines {
+		report.WriteString(line)
+	}
+	if !strings.Contains(report.String(), "<tr><td>2</td><td>^INFO:</td>") {
+		t.Errorf("Report appendix does not contain the hits of ^INFO:\n%s", report.String())
+	}
+
+	if err := app.saveRuleStats(now); err != nil {
+		t.Fatalf("saveRuleStats returned error: %v", err)
+	}
+	if app.rules.ignore[0].hits != 0 {
+		t.Error("Counters should be reset after saving")
+	}
+	app.classifyLine("INFO: next day")
+	if err := app.saveRuleStats(now.AddDate(0, 0, 1)); err != nil {
+		t.Fatalf("saveRuleStats returned error: %v", err)
+	}
+
+	history, err := readRuleStatsHistory(statsFile)
+	if err != nil {
+		t.Fatalf("readRuleStatsHistory returned error: %v", err)
+	}
+	if len(history.Rules) != 3 {
+		t.Errorf("Expected 3 rules in history (old hits pruned), got %+v", history.Rules)
+	}
+
+	var out bytes.Buffer
+	if err := app.WriteRuleStats(&out, 7, now.AddDate(0, 0, 1)); err != nil {
+		t.Fatalf("WriteRuleStats returned error: %v", err)
+	}
+	output := out.String()
+	if !strings.Contains(output, "3     ^INFO:") {
+		t.Errorf("Expected 3 hits for ^INFO:\n%s", output)
+	}
+	if !strings.Contains(output, "Rules without hits in the last 7 days: 1\n") ||
+		!strings.Contains(output, ": never matches") {
+		t.Errorf("Expected the dead rule to be listed\n%s", output)
+	}
+}
+
+// TestRuleStatsIgnoreHitsOfReportedLines tests that an ignore rule matching lines reported by
+// an alert rule has hits
+func TestRuleStatsIgnoreHitsOfReportedLines(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	app := New(context.Background(), configapp.AppConfig{}, aws.Config{}, 3600, logger)
+	app.rules = ruleSet{
+		ignore: mustCompileRules("OOMKilled"),
+		alert:  mustCompileRules("^CRITICAL:"),
+	}
+	if section := app.classifyLine("CRITICAL: container OOMKilled"); section != sectionCritical {
+		t.Errorf("Expected the line to be critical, got %v", section)
+	}
+	if app.rules.ignore[0].hits != 1 {
+		t.Errorf("Expected 1 hit for the ignore rule, got %d", app.rules.ignore[0].hits)
+	}
+}
@@ -0,0 +1,31 @@
+package app
+
+// reportSection is the part of the report a log line belongs to.
+// Sections are ordered as they appear in the report.
+type reportSection int
+
+const (
+	sectionIgnored  reportSection = iota // Line is not reported
+	sectionCritical                      // Alert rules
+	sectionAlerts                        // logcheck cracking.d
+	sectionSecurity                      // logcheck violations.d
+	sectionSystem                        // Lines matching no ignore rule
+)
+
+// reportSections lists the sections printed in the report, in order.
+var reportSections = []reportSection{sectionCritical, sectionAlerts, sectionSecurity, sectionSystem}
+
+func (s reportSection) title() string {
+	switch s {
+	case sectionCritical:
+		return "Critical Events"
+	case sectionAlerts:
+		return "Security Alerts"
+	case sectionSecurity:
+		return "Security Events"
+	case sectionSystem:
+		return "System Events"
+	case sectionIgnored:
+	}
+	return ""
+}
@@ -0,0 +1,192 @@
+package app
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Operators of the selector terms, longest first for parsing.
+var selectorOperators = []string{"=~", "!~", "!=", "="}
+
+// selectorTermStart matches the beginning of a term, to tell the commas separating the terms
+// from the ones of the regexps.
+var selectorTermStart = regexp.MustCompile(
+	`^\s*(namespace|pod|container|image|host|labels\.[^\s=!~,]+|annotations\.[^\s=!~,]+)\s*(=~|!~|!=|=)`)
+
+// selectorTerm is one key/operator/value condition of a selector.
+type selectorTerm struct {
+	key   string
+	op    string
+	value string
+	re    *regexp.Regexp // For =~ and !~
+}
+
+// selector matches the kubernetes metadata of an event when all its terms match,
+// e.g. "namespace=monitoring,labels.app=~^debug-".
+type selector struct {
+	raw   string
+	terms []selectorTerm
+}
+
+// parseSelector parses comma separated terms key<op>value, with op one of = != =~ !~.
+// Keys are namespace, pod, container, image, host, labels.<name> and annotations.<name>.
+func parseSelector(raw string) (selector, error) {
+	sel := selector{raw: raw}
+	for _, part := range splitSelector(raw) {
+		part = strings.TrimSpace(part)
+		term, err := parseSelectorTerm(part)
+		if err != nil {
+			return selector{}, err
+		}
+		sel.terms = append(sel.terms, term)
+	}
+	return sel, nil
+}
+
+// splitSelector splits raw into terms. In a regexp, a comma is a separator only if
+// a term starts after it: "labels.app=~^web-[0-9]{1,3}$,namespace=prod" has two terms.
+func splitSelector(raw string) []string {
+	var parts []string
+	start := 0
+	for i := range len(raw) {
+		if raw[i] != ',' {
+			continue
+		}
+		if _, op := selectorOperator(raw[start:i]); (op == "=~" || op == "!~") &&
+			!selectorTermStart.MatchString(raw[i+1:]) {
+			continue // Part of the regexp
+		}
+		parts = append(parts, raw[start:i])
+		start = i + 1
+	}
+	return append(parts, raw[start:])
+}
+
+// selectorOperator returns the position and the operator of a term, -1 if there is none.
+func selectorOperator(part string) (int, string) {
+	pos, op := -1, ""
+	for _, candidate := range selectorOperators {
+		if i := strings.Index(part, candidate); i > 0 && (pos == -1 || i < pos) {
+			pos, op = i, candidate
+		}
+	}
+	return pos, op
+}
+
+func parseSelectorTerm(part string) (selectorTerm, error) {
+	pos, op := selectorOperator(part)
+	if pos == -1 {
+		return selectorTerm{}, fmt.Errorf("%w: %q", ErrInvalidSelector, part)
+	}
+	term := selectorTerm{
+		key:   strings.TrimSpace(part[:pos]),
+		op:    op,
+		value: strings.TrimSpace(part[pos+len(op):]),
+	}
+	if !isSelectorKey(term.key) {
+		return selectorTerm{}, fmt.Errorf("%w: unknown key %q", ErrInvalidSelector, term.key)
+	}
+	if op == "=~" || op == "!~" {
+		re, err := regexp.Compile(term.value)
+		if err != nil {
+			return selectorTerm{}, fmt.Errorf("%w: %q: %w", ErrInvalidSelector, part, err)
+		}
+		term.re = re
+	}
+	return term, nil
+}
+
+func isSelectorKey(key string) bool {
+	switch key {
+	case "namespace", "pod", "container", "image", "host":
+		return true
+	}
+	for _, prefix := range []string{"labels.", "annotations."} {
+		if strings.HasPrefix(key, prefix) && len(key) > len(prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// field returns the value of key in infos, and whether it is set.
+func (t selectorTerm) field(infos kubernetesInfos) (string, bool) {
+	switch t.key {
+	case "namespace":
+		return infos.NamespaceName, infos.NamespaceName != ""
+	case "pod":
+		return infos.PodName, infos.PodName != ""
+	case "container":
+		return infos.ContainerName, infos.ContainerName != ""
+	case "image":
+		return infos.ContainerImage, infos.ContainerImage != ""
+	case "host":
+		return infos.Host, infos.Host != ""
+	}
+	if name, ok := strings.CutPrefix(t.key, "labels."); ok {
+		value, found := infos.Labels[name]
+		return value, found
+	}
+	name := strings.TrimPrefix(t.key, "annotations.")
+	value, found := infos.Annotations[name]
+	return value, found
+}
+
+// matches checks the term against infos. A missing label or annotation only
+// matches the negative operators.
+func (t selectorTerm) matches(infos kubernetesInfos) bool {
+	value, found := t.field(infos)
+	switch t.op {
+	case "=":
+		return found && value == t.value
+	case "!=":
+		return !found || value != t.value
+	case "=~":
+		return found && t.re.MatchString(value)
+	default: // !~
+		return !found || !t.re.MatchString(value)
+	}
+}
+
+// matches checks if all the terms of the selector match infos.
+func (s selector) matches(infos kubernetesInfos) bool {
+	for _, term := range s.terms {
+		if !term.matches(infos) {
+			return false
+		}
+	}
+	return true
+}
+
+// compileSelectors parses the ignore selectors of the configuration, incorrect ones are logged and skipped.
+func (a *App) compileSelectors() []selector {
+	selectors := make([]selector, 0, len(a.cfg.SelectorsToIgnore))
+	for _, raw := range a.cfg.SelectorsToIgnore {
+		sel, err := parseSelector(raw)
+		if err != nil {
+			a.appLog.Error("selector is incorrect",
+				slog.String("selector", raw),
+				slog.String("error", err.Error()))
+			a.skippedSelectors++
+			continue
+		}
+		selectors = append(selectors, sel)
+	}
+	return selectors
+}
+
+// isSelectorIgnored checks if one of the ignore selectors matches infos.
+func (a *App) isSelectorIgnored(infos kubernetesInfos) bool {
+	for _, sel := range a.ignoreSelectors {
+		if sel.matches(infos) {
+			a.appLog.Debug("Selector match",
+				slog.String("selector", sel.raw),
+				slog.String("namespace", infos.NamespaceName),
+				slog.String("pod", infos.PodName))
+			return true
+		}
+	}
+	return false
+}
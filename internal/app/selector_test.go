@@ -0,0 +1,89 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/sgaunet/awslogcheck/internal/configapp"
+)
+
+func TestSelectorMatches(t *testing.T) {
+	infos := kubernetesInfos{
+		PodName:        "debug-shell-7d9f",
+		ContainerImage: "busybox:1.36",
+		ContainerName:  "shell",
+		NamespaceName:  "monitoring",
+		Host:           "ip-10-0-1-12.eu-west-3.compute.internal",
+		Labels:         map[string]string{"app": "debug-shell", "team": "ops"},
+		Annotations:    map[string]string{"awslogcheck/ignore": "true"},
+	}
+
+	tests := []struct {
+		selector string
+		expected bool
+	}{
+		{"namespace=monitoring", true},
+		{"namespace=kube-system", false},
+		{"namespace!=kube-system", true},
+		{"pod=~^debug-", true},
+		{"image!~^busybox", false},
+		{"host=~^ip-10-0-1-", true},
+		{"labels.app=~^debug-", true},
+		{"labels.team=dev", false},
+		{"labels.missing!=x", true},
+		{"labels.missing=~.*", false},
+		{"annotations.awslogcheck/ignore=true", true},
+		{"namespace=monitoring, labels.team=ops", true},
+		{"namespace=monitoring,labels.team=dev", false},
+		{"labels.app=~^debug-[a-z]{1,5}$", true},
+		{"labels.app=~^debug-[a-z]{1,3}$", false},
+		{"pod=~^(debug|test),shell, namespace=monitoring", false},
+		{"labels.app=~^debug-(shell|sh),labels.team=~^(ops|dev)$", true},
+		{"labels.app=~^debug-(shell|sh),labels.team=~^(ops|dev)$,namespace=default", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			sel, err := parseSelector(tt.selector)
+			if err != nil {
+				t.Fatalf("parseSelector returned error: %v", err)
+			}
+			if result := sel.matches(infos); result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseSelectorErrors(t *testing.T) {
+	for _, raw := range []string{"namespace", "=kube-system", "node=a", "labels.=a", "pod=~(debug"} {
+		if _, err := parseSelector(raw); !errors.Is(err, ErrInvalidSelector) {
+			t.Errorf("%q: expected ErrInvalidSelector, got %v", raw, err)
+		}
+	}
+}
+
+func TestIsSelectorIgnored(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := configapp.AppConfig{
+		SelectorsToIgnore: []string{"namespace=kube-system", "pod=~(broken", "labels.app=~^debug-"},
+	}
+	app := New(context.Background(), cfg, aws.Config{}, 3600, logger)
+
+	if len(app.ignoreSelectors) != 2 {
+		t.Errorf("Expected the incorrect selector to be skipped, got %d selectors", len(app.ignoreSelectors))
+	}
+	if !app.isSelectorIgnored(kubernetesInfos{NamespaceName: "kube-system"}) {
+		t.Error("kube-system should be ignored")
+	}
+	if !app.isSelectorIgnored(kubernetesInfos{NamespaceName: "default", Labels: map[string]string{"app": "debug-1"}}) {
+		t.Error("debug workloads should be ignored")
+	}
+	if app.isSelectorIgnored(kubernetesInfos{NamespaceName: "default", Labels: map[string]string{"app": "api"}}) {
+		t.Error("api should not be ignored")
+	}
+}
@@ -0,0 +1,282 @@
+package app
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+)
+
+// Limits of the events kept in memory, the overflow is spilled to disk.
+const (
+	defaultMaxStreamEvents = 10000  // Events of a stream
+	defaultMaxEvents       = 100000 // Events of all the streams
+)
+
+// spillFile holds the events of a stream spilled to disk, as sorted runs of JSON lines.
+// It is only open while a run is written or the events are read, so that the spilled
+// streams don't each hold a file descriptor.
+type spillFile struct {
+	name string
+	runs []spillRun
+	size int64
+}
+
+// spillRun is a sorted sequence of events in the spill file.
+type spillRun struct {
+	offset, size int64
+}
+
+// spilledEvent is the encoding of a logEvent in a spill file.
+type spilledEvent struct {
+	Timestamp int64         `json:"t"`
+	Message   string        `json:"m"`
+	Section   reportSection `json:"s"`
+	Context   bool          `json:"c,omitempty"`
+}
+
+func (a *App) maxStreamEvents() int {
+	if a.cfg.Memory.MaxStreamEvents > 0 {
+		return a.cfg.Memory.MaxStreamEvents
+	}
+	return defaultMaxStreamEvents
+}
+
+func (a *App) maxEvents() int {
+	if a.cfg.Memory.MaxEvents > 0 {
+		return a.cfg.Memory.MaxEvents
+	}
+	return defaultMaxEvents
+}
+
+// appendEvent adds an event to stream, and spills its events to disk when the limit of the
+// stream is reached.
+func (a *App) appendEvent(stream *streamEvents, event logEvent) {
+	if stream.hasIgnoredContainer && (event.context || event.section != sectionCritical) {
+		return
+	}
+	stream.events = append(stream.events, event)
+	if !event.context {
+		if stream.sectionCounts == nil {
+			stream.sectionCounts = make(map[reportSection]int)
+		}
+		stream.sectionCounts[event.section]++
+	}
+	a.eventsInMemory++
+	if len(stream.events) >= a.maxStreamEvents() {
+		a.spillStream(stream)
+	}
+}
+
+// spillLargestStreams spills the streams having the most events in memory when the limit
+// of all the streams is reached, until half of the limit is reached.
+func (a *App) spillLargestStreams(streamGroups map[string]*streamEvents) {
+	if a.eventsInMemory < max(a.maxEvents(), a.spillThreshold) {
+		return
+	}
+	streams := make([]*streamEvents, 0, len(streamGroups))
+	for _, stream := range streamGroups {
+		if len(stream.events) > 0 && !stream.unspillable {
+			streams = append(streams, stream)
+		}
+	}
+	sort.Slice(streams, func(i, j int) bool {
+		if len(streams[i].events) != len(streams[j].events) {
+			return len(streams[i].events) > len(streams[j].events)
+		}
+		return streams[i].streamName < streams[j].streamName
+	})
+	for _, stream := range streams {
+		if a.eventsInMemory <= a.maxEvents()/2 {
+			break
+		}
+		a.spillStream(stream)
+	}
+	// The events of the streams that can't be spilled stay in memory: the next pass waits
+	// for half of the limit more, instead of sorting the streams again at every event
+	a.spillThreshold = a.eventsInMemory + a.maxEvents()/2
+}
+
+// spillStream writes the events of stream in memory to its spill file, as a new sorted run.
+// If the file can't be written, the events stay in memory and the stream is not spilled anymore.
+func (a *App) spillStream(stream *streamEvents) {
+	if stream.unspillable {
+		return
+	}
+	if err := a.writeSpillRun(stream); err != nil {
+		a.appLog.Error("Failed to spill events, the events of the stream are kept in memory",
+			slog.String("streamName", stream.streamName), slog.String("error", err.Error()))
+		stream.unspillable = true
+		return
+	}
+	a.appLog.Debug("Events spilled to disk",
+		slog.String("streamName", stream.streamName), slog.Int("events", len(stream.events)))
+	a.eventsInMemory -= len(stream.events)
+	stream.events = stream.events[:0:0]
+}
+
+// writeSpillRun appends the sorted events of stream in memory to its spill file.
+func (a *App) writeSpillRun(stream *streamEvents) error {
+	var file *os.File
+	var err error
+	if stream.spill == nil {
+		file, err = os.CreateTemp(a.cfg.Memory.SpillDir, "awslogcheck-spill-*")
+		if err != nil {
+			return fmt.Errorf("failed to create spill file: %w", err)
+		}
+		stream.spill = &spillFile{name: file.Name()}
+	} else {
+		// #nosec G304 - spill file created above
+		file, err = os.OpenFile(stream.spill.name, os.O_WRONLY, 0)
+		if err != nil {
+			return fmt.Errorf("failed to open spill file: %w", err)
+		}
+	}
+	sortEvents(stream.events)
+	size, err := writeEvents(io.NewOffsetWriter(file, stream.spill.size), stream.events)
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to close spill file: %w", closeErr)
+	}
+	if err != nil {
+		return err
+	}
+	stream.spill.runs = append(stream.spill.runs, spillRun{offset: stream.spill.size, size: size})
+	stream.spill.size += size
+	return nil
+}
+
+// writeEvents writes events as JSON lines, and returns the number of bytes written.
+func writeEvents(w io.Writer, events []logEvent) (int64, error) {
+	buffered := bufio.NewWriter(w)
+	counter := &countingWriter{w: buffered}
+	enc := json.NewEncoder(counter)
+	for _, event := range events {
+		err := enc.Encode(spilledEvent{
+			Timestamp: event.timestamp, Message: event.message, Section: event.section, Context: event.context,
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to write spill file: %w", err)
+		}
+	}
+	if err := buffered.Flush(); err != nil {
+		return 0, fmt.Errorf("failed to write spill file: %w", err)
+	}
+	return counter.n, nil
+}
+
+// forEachEvent calls fn with the events of stream sorted by timestamp, merging the runs spilled
+// to disk with the events in memory. Events having the same timestamp stay in the order they
+// were added.
+func (a *App) forEachEvent(stream *streamEvents, fn func(logEvent)) error {
+	sortEvents(stream.events)
+	if stream.spill == nil {
+		for _, event := range stream.events {
+			fn(event)
+		}
+		return nil
+	}
+
+	// #nosec G304 - spill file created by writeSpillRun
+	file, err := os.Open(stream.spill.name)
+	if err != nil {
+		return fmt.Errorf("failed to open spill file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	heads := make([]*eventIterator, 0, len(stream.spill.runs)+1)
+	for _, run := range stream.spill.runs {
+		dec := json.NewDecoder(bufio.NewReader(io.NewSectionReader(file, run.offset, run.size)))
+		heads = append(heads, &eventIterator{dec: dec})
+	}
+	heads = append(heads, &eventIterator{events: stream.events})
+	for _, head := range heads {
+		if err := head.advance(); err != nil {
+			return err
+		}
+	}
+	for {
+		var next *eventIterator
+		for _, head := range heads {
+			if head.ok && (next == nil || head.event.timestamp < next.event.timestamp) {
+				next = head
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		fn(next.event)
+		if err := next.advance(); err != nil {
+			return err
+		}
+	}
+}
+
+// releaseStreams removes the spill files of the streams.
+func (a *App) releaseStreams(streamGroups map[string]*streamEvents) {
+	for _, stream := range streamGroups {
+		if stream.spill == nil {
+			continue
+		}
+		if err := os.Remove(stream.spill.name); err != nil {
+			a.appLog.Error("Failed to remove spill file", slog.String("error", err.Error()))
+		}
+		stream.spill = nil
+	}
+	a.eventsInMemory = 0
+	a.spillThreshold = 0
+}
+
+func sortEvents(events []logEvent) {
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].timestamp < events[j].timestamp
+	})
+}
+
+// eventIterator reads a sorted run of events, from a spill file or from memory.
+type eventIterator struct {
+	dec    *json.Decoder
+	events []logEvent
+	event  logEvent
+	ok     bool
+}
+
+func (it *eventIterator) advance() error {
+	if it.dec == nil {
+		it.ok = len(it.events) > 0
+		if it.ok {
+			it.event, it.events = it.events[0], it.events[1:]
+		}
+		return nil
+	}
+	var spilled spilledEvent
+	if err := it.dec.Decode(&spilled); err != nil {
+		it.ok = false
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		return fmt.Errorf("failed to read spill file: %w", err)
+	}
+	it.event = logEvent{
+		timestamp: spilled.Timestamp, message: spilled.Message, section: spilled.Section, context: spilled.Context,
+	}
+	it.ok = true
+	return nil
+}
+
+// countingWriter counts the bytes written.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	if err != nil {
+		return n, fmt.Errorf("failed to write: %w", err)
+	}
+	return n, nil
+}
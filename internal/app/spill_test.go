@@ -0,0 +1,114 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/sgaunet/awslogcheck/internal/configapp"
+)
+
+func TestSpillToDiskKeepsTheReport(t *testing.T) {
+	now := time.Now().Unix() * 1000
+	var events []types.FilteredLogEvent
+	for i := range 120 {
+		msg := fmt.Sprintf("ERROR: event %d", i)
+		switch {
+		case i%5 == 0:
+			msg = fmt.Sprintf("INFO: event %d", i)
+		case i%7 == 0:
+			msg = fmt.Sprintf("CRITICAL: event %d", i)
+		}
+		// Some events are out of order, or share their timestamp
+		ts := now - 600000 + int64(i/2)*1000
+		if i%11 == 0 {
+			ts -= 30000
+		}
+		events = append(events, createLogEvent(ts, fmt.Sprintf("stream-%d", i%3), "pod", "app:latest", "app", msg))
+	}
+
+	run := func(memory configapp.MemoryConfig) []string {
+		logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+		app := New(context.Background(), configapp.AppConfig{Memory: memory, ContextLines: 1}, aws.Config{}, 3600, logger)
+		app.rules = ruleSet{ignore: mustCompileRules("^INFO:"), alert: mustCompileRules("^CRITICAL:")}
+		chLogLines := make(chan string, 10000)
+		_, err := app.parseAllEventsWithFilterClient(context.Background(), &mockCloudWatchClient{events: events, pageSize: 10},
+			"test-group", now-3600000, now, chLogLines)
+		if err != nil {
+			t.Fatalf("parseAllEventsWithFilterClient returned error: %v", err)
+		}
+		close(chLogLines)
+		var lines []string
+		for line := range chLogLines {
+			lines = append(lines, line)
+		}
+		return lines
+	}
+
+	spillDir := t.TempDir()
+	inMemory := run(configapp.MemoryConfig{})
+	spilled := run(configapp.MemoryConfig{MaxStreamEvents: 4, MaxEvents: 7, SpillDir: spillDir})
+	if len(inMemory) == 0 || strings.Join(inMemory, "") != strings.Join(spilled, "") {
+		t.Errorf("The report differs when events are spilled:\n%s\n---\n%s",
+			strings.Join(inMemory, ""), strings.Join(spilled, ""))
+	}
+	files, err := os.ReadDir(spillDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 0 {
+		t.Errorf("Spill files should be removed, found %d", len(files))
+	}
+}
+
+func TestIgnoredStreamReleasesItsEvents(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	app := New(context.Background(), configapp.AppConfig{ImagesToIgnore: []string{"sidecar"}}, aws.Config{}, 3600, logger)
+	now := time.Now().Unix() * 1000
+	streamGroups := make(map[string]*streamEvents)
+	eventCount := 0
+	app.processEventsInPage([]types.FilteredLogEvent{
+		createLogEvent(now-3000, "stream-1", "pod", "app:latest", "app", "ERROR: a"),
+		createLogEvent(now-2000, "stream-1", "pod", "sidecar:latest", "sidecar", "ERROR: b"),
+		createLogEvent(now-1000, "stream-1", "pod", "app:latest", "app", "ERROR: c"),
+	}, streamGroups, &eventCount)
+
+	if len(streamGroups["stream-1"].events) != 0 || app.eventsInMemory != 0 {
+		t.Errorf("Events of an ignored stream should be released, %d in memory", app.eventsInMemory)
+	}
+}
+
+func TestSpillFailureKeepsTheEvents(t *testing.T) {
+	var logs strings.Builder
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+	memory := configapp.MemoryConfig{MaxStreamEvents: 2, MaxEvents: 3, SpillDir: filepath.Join(t.TempDir(), "missing")}
+	app := New(context.Background(), configapp.AppConfig{Memory: memory}, aws.Config{}, 3600, logger)
+	now := time.Now().Unix() * 1000
+	var events []types.FilteredLogEvent
+	for i := range 20 {
+		events = append(events, createLogEvent(now-int64(20-i)*1000, fmt.Sprintf("stream-%d", i%2),
+			"pod", "app:latest", "app", fmt.Sprintf("ERROR: event %d", i)))
+	}
+	streamGroups := make(map[string]*streamEvents)
+	eventCount := 0
+	app.processEventsInPage(events, streamGroups, &eventCount)
+	defer app.releaseStreams(streamGroups)
+
+	if app.eventsInMemory != 20 {
+		t.Errorf("Expected the 20 events to stay in memory, got %d", app.eventsInMemory)
+	}
+	if failures := strings.Count(logs.String(), "Failed to spill events"); failures != 2 {
+		t.Errorf("Expected one spill failure by stream, got %d", failures)
+	}
+	if app.spillThreshold <= app.eventsInMemory {
+		t.Errorf("The next spill should wait for more events, threshold %d", app.spillThreshold)
+	}
+}
@@ -8,8 +8,12 @@ type fluentDockerLog struct {
 
 // Subpart of fluent Docker logs.
 type kubernetesInfos struct {
-	PodName        string `json:"pod_name"`
-	ContainerImage string `json:"container_image"`
-	ContainerName  string `json:"container_name"`
-	NamespaceName  string `json:"namespace_name"`
+	PodName        string            `json:"pod_name"`
+	ContainerImage string            `json:"container_image"`
+	ContainerName  string            `json:"container_name"`
+	NamespaceName  string            `json:"namespace_name"`
+	PodID          string            `json:"pod_id"`
+	Host           string            `json:"host"`
+	Labels         map[string]string `json:"labels"`
+	Annotations    map[string]string `json:"annotations"`
 }
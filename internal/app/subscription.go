@@ -0,0 +1,71 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+// controlMessageType is the type of the messages checking the destination of a subscription filter.
+const controlMessageType = "CONTROL_MESSAGE"
+
+// HandleSubscription checks the events sent by a CloudWatch Logs subscription filter, and
+// sends the lines to report. It's the handler of the Lambda function: an error if the report
+// can't be sent makes Lambda retry the invocation.
+func (a *App) HandleSubscription(_ context.Context, event events.CloudwatchLogsEvent) error {
+	batch, err := a.subscriptionBatch(event)
+	if err != nil {
+		return err
+	}
+	if len(batch) == 0 {
+		return nil
+	}
+	headers := make(map[string]string, len(batch))
+	for groupName := range batch {
+		headers[groupName] = logGroupHeader(groupName)
+	}
+	err = a.reportBatch(batch, headers)
+	for _, streamGroups := range batch {
+		a.releaseStreams(streamGroups)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to send report: %w", err)
+	}
+	return nil
+}
+
+// subscriptionBatch decodes the payload of a subscription filter, and returns the streams of
+// its events by log group, none for a control message.
+func (a *App) subscriptionBatch(event events.CloudwatchLogsEvent) (map[string]map[string]*streamEvents, error) {
+	data, err := event.AWSLogs.Parse()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode subscription payload: %w", err)
+	}
+	if data.MessageType == controlMessageType {
+		a.appLog.Debug("Subscription control message", slog.Int("events", len(data.LogEvents)))
+		return map[string]map[string]*streamEvents{}, nil
+	}
+	a.appLog.Debug("Subscription events",
+		slog.String("owner", data.Owner),
+		slog.String("groupName", data.LogGroup),
+		slog.String("streamName", data.LogStream),
+		slog.Int("events", len(data.LogEvents)))
+
+	logEvents := make([]types.FilteredLogEvent, 0, len(data.LogEvents))
+	for _, logEvent := range data.LogEvents {
+		logEvents = append(logEvents, types.FilteredLogEvent{
+			EventId:       aws.String(logEvent.ID),
+			LogStreamName: aws.String(data.LogStream),
+			Message:       aws.String(logEvent.Message),
+			Timestamp:     aws.Int64(logEvent.Timestamp),
+		})
+	}
+	streamGroups := make(map[string]*streamEvents)
+	eventCount := 0
+	a.processEventsInPage(logEvents, streamGroups, &eventCount)
+	return map[string]map[string]*streamEvents{data.LogGroup: streamGroups}, nil
+}
@@ -0,0 +1,134 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/sgaunet/awslogcheck/internal/configapp"
+)
+
+// readSubscriptionFixture reads a payload of a subscription filter recorded in testdata.
+func readSubscriptionFixture(t *testing.T, name string) events.CloudwatchLogsEvent {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join("testdata", "subscription", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var event events.CloudwatchLogsEvent
+	if err := json.Unmarshal(content, &event); err != nil {
+		t.Fatal(err)
+	}
+	return event
+}
+
+func TestSubscriptionBatch(t *testing.T) {
+	tests := []struct {
+		fixture     string
+		printed     int
+		expected    []string
+		notExpected []string
+	}{
+		{
+			fixture: "data.json",
+			printed: 2,
+			expected: []string{
+				"<h1>Log group: /aws/containerinsights/prod-EKS/application</h1>",
+				"<b>Parse stream</b> :api-7d9f8b6c5-x2x4q_shop_api-3f1c2a9d0b",
+				"2025-10-18 07:00:00 UTC: ERROR: payment gateway timeout after 30s",
+				"2025-10-18 07:00:00 UTC: ERROR: failed to commit order 8812: connection refused",
+			},
+			notExpected: []string{"INFO:"},
+		},
+		{fixture: "control.json", notExpected: []string{"CWL CONTROL MESSAGE"}},
+		{fixture: "ignored.json", notExpected: []string{"buffer flush failed"}},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			cfg := configapp.AppConfig{ImagesToIgnore: []string{"fluent/fluentd-kubernetes-daemonset"}}
+			app := New(context.Background(), cfg, aws.Config{}, 3600, logger)
+			app.rules = ruleSet{ignore: mustCompileRules("^INFO:")}
+
+			batch, err := app.subscriptionBatch(readSubscriptionFixture(t, tt.fixture))
+			if err != nil {
+				t.Fatalf("subscriptionBatch returned error: %v", err)
+			}
+			headers := make(map[string]string)
+			for groupName := range batch {
+				headers[groupName] = logGroupHeader(groupName)
+			}
+			chLogLines := make(chan string, 100)
+			printed := app.writeBatch(batch, headers, chLogLines)
+			close(chLogLines)
+			var report strings.Builder
+			for line := range chLogLines {
+				report.WriteString(line)
+			}
+			for _, expected := range tt.expected {
+				if !strings.Contains(report.String(), expected) {
+					t.Errorf("Expected %q in the report:\n%s", expected, report.String())
+				}
+			}
+			for _, notExpected := range tt.notExpected {
+				if strings.Contains(report.String(), notExpected) {
+					t.Errorf("Unexpected %q in the report:\n%s", notExpected, report.String())
+				}
+			}
+			if printed != tt.printed {
+				t.Errorf("Expected %d lines printed, got %d", tt.printed, printed)
+			}
+		})
+	}
+}
+
+func TestSubscriptionBatchInvalidPayload(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	app := New(context.Background(), configapp.AppConfig{}, aws.Config{}, 3600, logger)
+	for _, data := range []string{"not base64!", "bm90IGd6aXBwZWQ="} {
+		event := events.CloudwatchLogsEvent{AWSLogs: events.CloudwatchLogsRawData{Data: data}}
+		if _, err := app.subscriptionBatch(event); err == nil {
+			t.Errorf("Expected an error for the payload %q", data)
+		}
+	}
+}
+
+func TestHandleSubscription(t *testing.T) {
+	// Nothing listens on the port: the connection is refused
+	unreachable := configapp.AppConfig{
+		MailConfig: configapp.MailConfiguration{Sendto: "ops@example.com", FromEmail: "awslogcheck@example.com"},
+	}
+	unreachable.SMTPConfig.Server = "127.0.0.1"
+	unreachable.SMTPConfig.Port = 1
+	unreachable.SMTPConfig.Login = "login"
+	unreachable.SMTPConfig.Password = "password"
+	unreachable.SMTPConfig.MaxReportSize = 1 << 20
+	tests := []struct {
+		name    string
+		fixture string
+		cfg     configapp.AppConfig
+		wantErr bool
+	}{
+		{"sent", "data.json", configapp.AppConfig{}, false},
+		{"send failed", "data.json", unreachable, true},
+		{"control message", "control.json", unreachable, false},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := New(context.Background(), tt.cfg, aws.Config{}, 3600, logger)
+			app.rules = ruleSet{ignore: mustCompileRules("^INFO:")}
+			err := app.HandleSubscription(context.Background(), readSubscriptionFixture(t, tt.fixture))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
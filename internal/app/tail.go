@@ -0,0 +1,326 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+// Defaults of the live tail mode.
+const (
+	defaultTailInterval   = 60 // Seconds, maximum delay of a notification
+	defaultTailDebounce   = 10 // Seconds without new line to report before a notification is sent
+	maxLiveTailLogGroups  = 10 // Log groups of a Live Tail session
+	tailEventsChannelSize = 1000
+)
+
+// LiveTailClient interface for testing.
+type LiveTailClient interface {
+	StartLiveTail(ctx context.Context,
+		params *cloudwatchlogs.StartLiveTailInput) (cloudwatchlogs.StartLiveTailResponseStreamReader, error)
+}
+
+// liveTailClient starts the Live Tail sessions with a CloudWatch Logs client.
+type liveTailClient struct {
+	client *cloudwatchlogs.Client
+}
+
+// StartLiveTail starts a session, and returns the stream of its events.
+func (c liveTailClient) StartLiveTail(ctx context.Context,
+	params *cloudwatchlogs.StartLiveTailInput) (cloudwatchlogs.StartLiveTailResponseStreamReader, error) {
+	output, err := c.client.StartLiveTail(ctx, params)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // wrapped by the caller, once retries are exhausted
+	}
+	return output.GetStream(), nil
+}
+
+// tailSession is a Live Tail session of log groups of the same target and filter pattern.
+type tailSession struct {
+	client        LiveTailClient
+	groupARNs     []string
+	filterPattern string
+}
+
+// tailEvent is an event received by a Live Tail session.
+type tailEvent struct {
+	groupARN string
+	event    types.FilteredLogEvent
+}
+
+// Tail applies the rules to the events of the log groups as they are ingested, and sends
+// the lines to report in micro-batches, until ctx is done.
+func (a *App) Tail(ctx context.Context) error {
+	checks, errs := a.resolveTargets(ctx)
+	for _, err := range errs {
+		a.appLog.Error(err.Error())
+	}
+	if len(checks) == 0 {
+		return errors.Join(errs...)
+	}
+	sessions := a.tailSessions(checks, func(client *cloudwatchlogs.Client) LiveTailClient {
+		return liveTailClient{client: client}
+	})
+	headers := make(map[string]string, len(checks))
+	for _, check := range checks {
+		headers[check.groupARN] = check.header(len(checks))
+	}
+	return a.tail(ctx, sessions, headers)
+}
+
+// tailSessions returns the sessions tailing the log groups of checks, grouped by target
+// and filter pattern.
+func (a *App) tailSessions(checks []logGroupCheck,
+	newClient func(*cloudwatchlogs.Client) LiveTailClient) []*tailSession {
+	type sessionKey struct {
+		client        *cloudwatchlogs.Client
+		filterPattern string
+	}
+	var sessions []*tailSession
+	current := make(map[sessionKey]*tailSession)
+	for _, check := range checks {
+		if check.groupARN == "" {
+			a.appLog.Warn("Log group without ARN, not tailed", slog.String("groupName", check.groupName))
+			continue
+		}
+		key := sessionKey{client: check.client, filterPattern: a.filterPattern(check.groupName)}
+		session, ok := current[key]
+		if !ok || len(session.groupARNs) == maxLiveTailLogGroups {
+			session = &tailSession{client: newClient(check.client), filterPattern: key.filterPattern}
+			current[key] = session
+			sessions = append(sessions, session)
+		}
+		session.groupARNs = append(session.groupARNs, check.groupARN)
+	}
+	return sessions
+}
+
+// tailDelays returns the maximum delay of a notification, and the debounce delay.
+func (a *App) tailDelays() (time.Duration, time.Duration) {
+	interval := a.cfg.Tail.Interval
+	if interval <= 0 {
+		interval = defaultTailInterval
+	}
+	debounce := a.cfg.Tail.Debounce
+	if debounce <= 0 {
+		debounce = defaultTailDebounce
+	}
+	return time.Duration(interval) * time.Second, time.Duration(debounce) * time.Second
+}
+
+// tail runs the sessions until ctx is done or one of them fails, and reports their events.
+func (a *App) tail(ctx context.Context, sessions []*tailSession, headers map[string]string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events := make(chan tailEvent, tailEventsChannelSize)
+	errs := make(chan error, len(sessions))
+	var wg sync.WaitGroup
+	for _, session := range sessions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := a.runTailSession(ctx, session, events); err != nil {
+				errs <- err
+				cancel()
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(events)
+	}()
+
+	interval, debounce := a.tailDelays()
+	a.batchTailEvents(events, interval, debounce, func(batch map[string]map[string]*streamEvents) {
+		_ = a.reportBatch(batch, headers) // Logged, the next batches are still reported
+	})
+	close(errs)
+	var failures []error
+	for err := range errs {
+		failures = append(failures, err)
+	}
+	return errors.Join(failures...)
+}
+
+// runTailSession sends the events of session until ctx is done. The session is started
+// again when Live Tail closes it (after 3 hours at most), or its stream fails.
+func (a *App) runTailSession(ctx context.Context, session *tailSession, events chan<- tailEvent) error {
+	input := &cloudwatchlogs.StartLiveTailInput{LogGroupIdentifiers: session.groupARNs}
+	if session.filterPattern != "" {
+		input.LogEventFilterPattern = aws.String(session.filterPattern)
+	}
+	attempt := 1
+	for {
+		var stream cloudwatchlogs.StartLiveTailResponseStreamReader
+		err := a.withRetry(ctx, "StartLiveTail", a.logGroupRateLimit, maxLogGroupAPICallPerSecond, func() error {
+			var err error
+			stream, err = session.client.StartLiveTail(ctx, input)
+			return err //nolint:wrapcheck // wrapped below, once retries are exhausted
+		})
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to start live tail of %v: %w", session.groupARNs, err)
+		}
+
+		received, err := a.readTailStream(ctx, session, stream, events)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if received {
+			attempt = 1
+		}
+		delay := a.backoff(attempt)
+		attempt++
+		if err != nil {
+			a.appLog.Warn("Live tail session failed, restarting", slog.Any("logGroups", session.groupARNs),
+				slog.Duration("delay", delay), slog.String("error", err.Error()))
+		} else {
+			a.appLog.Debug("Live tail session closed, restarting", slog.Any("logGroups", session.groupARNs))
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+	}
+}
+
+// readTailStream sends the events of stream until it is closed or ctx is done, and
+// reports whether any was received.
+func (a *App) readTailStream(ctx context.Context, session *tailSession,
+	stream cloudwatchlogs.StartLiveTailResponseStreamReader, events chan<- tailEvent) (bool, error) {
+	defer func() {
+		if err := stream.Close(); err != nil {
+			a.appLog.Debug("Failed to close live tail stream", slog.String("error", err.Error()))
+		}
+	}()
+	received := false
+	sampled := false
+	for {
+		var item types.StartLiveTailResponseStream
+		select {
+		case <-ctx.Done():
+			return received, nil
+		case next, ok := <-stream.Events():
+			if !ok {
+				if err := stream.Err(); err != nil {
+					return received, fmt.Errorf("failed to read live tail stream: %w", err)
+				}
+				return received, nil
+			}
+			item = next
+		}
+
+		switch v := item.(type) {
+		case *types.StartLiveTailResponseStreamMemberSessionStart:
+			a.appLog.Debug("Live tail session started", slog.String("sessionID", aws.ToString(v.Value.SessionId)),
+				slog.Any("logGroups", session.groupARNs))
+		case *types.StartLiveTailResponseStreamMemberSessionUpdate:
+			received = true
+			if v.Value.SessionMetadata != nil && v.Value.SessionMetadata.Sampled && !sampled {
+				sampled = true
+				a.appLog.Warn("Live tail events are sampled, some are not checked",
+					slog.Any("logGroups", session.groupARNs))
+			}
+			for _, result := range v.Value.SessionResults {
+				event := tailEvent{
+					groupARN: session.groupARN(aws.ToString(result.LogGroupIdentifier)),
+					event: types.FilteredLogEvent{
+						LogStreamName: result.LogStreamName,
+						Message:       result.Message,
+						Timestamp:     result.Timestamp,
+						IngestionTime: result.IngestionTime,
+					},
+				}
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return received, nil
+				}
+			}
+		}
+	}
+}
+
+// groupARN returns the ARN of the log group of session identified by identifier, its name or ARN.
+func (s *tailSession) groupARN(identifier string) string {
+	for _, arn := range s.groupARNs {
+		if identifier == arn || strings.HasSuffix(arn, ":log-group:"+identifier) {
+			return arn
+		}
+	}
+	if len(s.groupARNs) == 1 {
+		return s.groupARNs[0]
+	}
+	return identifier
+}
+
+// batchTailEvents applies the rules to events until the channel is closed, and calls report
+// with the streams of the log groups: once no line to report was added for debounce, and at
+// least every interval.
+func (a *App) batchTailEvents(events <-chan tailEvent, interval, debounce time.Duration,
+	report func(batch map[string]map[string]*streamEvents)) {
+	batch := make(map[string]map[string]*streamEvents) // Streams by log group ARN
+	flush := func() {
+		if len(batch) > 0 {
+			report(batch)
+			for _, streamGroups := range batch {
+				a.releaseStreams(streamGroups)
+			}
+			clear(batch)
+		}
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	debounceTimer := time.NewTimer(debounce)
+	debounceTimer.Stop()
+	defer debounceTimer.Stop()
+
+	eventCount := 0
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				flush()
+				return
+			}
+			streamGroups := batch[e.groupARN]
+			if streamGroups == nil {
+				streamGroups = make(map[string]*streamEvents)
+				batch[e.groupARN] = streamGroups
+			}
+			streamName := aws.ToString(e.event.LogStreamName)
+			before := reportedLines(streamGroups[streamName])
+			a.processEventsInPage([]types.FilteredLogEvent{e.event}, streamGroups, &eventCount)
+			if reportedLines(streamGroups[streamName]) > before {
+				debounceTimer.Reset(debounce)
+			}
+		case <-debounceTimer.C:
+			flush()
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// reportedLines returns the number of lines of stream to report, context lines excluded.
+func reportedLines(stream *streamEvents) int {
+	if stream == nil {
+		return 0
+	}
+	count := 0
+	for _, sectionCount := range stream.sectionCounts {
+		count += sectionCount
+	}
+	return count
+}
@@ -0,0 +1,199 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/smithy-go"
+	"github.com/sgaunet/awslogcheck/internal/configapp"
+)
+
+// mockTailStream is a Live Tail stream sending its items, then closed with err.
+type mockTailStream struct {
+	events chan types.StartLiveTailResponseStream
+	err    error
+}
+
+func newMockTailStream(items []types.StartLiveTailResponseStream, err error) *mockTailStream {
+	stream := &mockTailStream{events: make(chan types.StartLiveTailResponseStream, len(items)), err: err}
+	for _, item := range items {
+		stream.events <- item
+	}
+	close(stream.events)
+	return stream
+}
+
+func (s *mockTailStream) Events() <-chan types.StartLiveTailResponseStream { return s.events }
+func (s *mockTailStream) Close() error                                     { return nil }
+func (s *mockTailStream) Err() error                                       { return s.err }
+
+// mockTailClient returns its streams, then fails with err.
+type mockTailClient struct {
+	mu      sync.Mutex
+	streams []*mockTailStream
+	err     error
+	inputs  []*cloudwatchlogs.StartLiveTailInput
+}
+
+func (m *mockTailClient) StartLiveTail(_ context.Context,
+	params *cloudwatchlogs.StartLiveTailInput) (cloudwatchlogs.StartLiveTailResponseStreamReader, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inputs = append(m.inputs, params)
+	if len(m.inputs) > len(m.streams) {
+		return nil, m.err
+	}
+	return m.streams[len(m.inputs)-1], nil
+}
+
+// liveTailUpdate returns a session update with the events of createLogEvent.
+func liveTailUpdate(groupIdentifier string, events ...types.FilteredLogEvent) types.StartLiveTailResponseStream {
+	update := &types.StartLiveTailResponseStreamMemberSessionUpdate{}
+	for _, event := range events {
+		update.Value.SessionResults = append(update.Value.SessionResults, types.LiveTailSessionLogEvent{
+			LogGroupIdentifier: aws.String(groupIdentifier),
+			LogStreamName:      event.LogStreamName,
+			Message:            event.Message,
+			Timestamp:          event.Timestamp,
+		})
+	}
+	return update
+}
+
+func TestTailSessions(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	app := New(context.Background(), configapp.AppConfig{FilterPatterns: map[string]string{"/filtered": "ERROR"}},
+		aws.Config{}, 3600, logger)
+	clientA, clientB := &cloudwatchlogs.Client{}, &cloudwatchlogs.Client{}
+	var checks []logGroupCheck
+	for i := range 12 {
+		name := fmt.Sprintf("/group-%02d", i)
+		checks = append(checks, logGroupCheck{client: clientA, groupName: name, groupARN: "arn:a:log-group:" + name})
+	}
+	checks = append(checks,
+		logGroupCheck{client: clientA, groupName: "/filtered", groupARN: "arn:a:log-group:/filtered"},
+		logGroupCheck{client: clientB, groupName: "/group-00", groupARN: "arn:b:log-group:/group-00"},
+		logGroupCheck{client: clientB, groupName: "/no-arn"},
+	)
+
+	sessions := app.tailSessions(checks, func(*cloudwatchlogs.Client) LiveTailClient { return &mockTailClient{} })
+	var got []string
+	for _, session := range sessions {
+		got = append(got, fmt.Sprintf("%d:%s", len(session.groupARNs), session.filterPattern))
+	}
+	if strings.Join(got, ",") != "10:,2:,1:ERROR,1:" {
+		t.Errorf("Unexpected sessions %v", got)
+	}
+}
+
+func TestRunTailSession(t *testing.T) {
+	now := time.Now().UnixMilli()
+	denied := &smithy.GenericAPIError{Code: "AccessDeniedException"}
+	client := &mockTailClient{
+		streams: []*mockTailStream{
+			newMockTailStream([]types.StartLiveTailResponseStream{
+				&types.StartLiveTailResponseStreamMemberSessionStart{},
+				liveTailUpdate("arn:aws:logs:eu-west-3:1:log-group:/b",
+					createLogEvent(now, "stream", "pod", "app:latest", "app", "ERROR: first")),
+			}, nil),
+			newMockTailStream([]types.StartLiveTailResponseStream{
+				liveTailUpdate("/a", createLogEvent(now+1, "stream", "pod", "app:latest", "app", "ERROR: second")),
+			}, errors.New("connection reset")),
+		},
+		err: denied,
+	}
+	session := &tailSession{
+		client:        client,
+		groupARNs:     []string{"arn:aws:logs:eu-west-3:1:log-group:/a", "arn:aws:logs:eu-west-3:1:log-group:/b"},
+		filterPattern: "ERROR",
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	app := New(context.Background(), configapp.AppConfig{Retry: configapp.RetryConfig{BaseDelay: 1}},
+		aws.Config{}, 3600, logger)
+
+	events := make(chan tailEvent, 10)
+	err := app.runTailSession(context.Background(), session, events)
+	if !errors.Is(err, denied) {
+		t.Fatalf("Expected the error of StartLiveTail, got %v", err)
+	}
+	close(events)
+	var got []string
+	for event := range events {
+		got = append(got, event.groupARN)
+	}
+	if strings.Join(got, ",") != strings.Join([]string{session.groupARNs[1], session.groupARNs[0]}, ",") {
+		t.Errorf("Unexpected log groups of the events %v", got)
+	}
+	if len(client.inputs) != 3 || aws.ToString(client.inputs[0].LogEventFilterPattern) != "ERROR" {
+		t.Errorf("Expected 3 sessions filtered by ERROR, got %d", len(client.inputs))
+	}
+}
+
+func TestBatchTailEvents(t *testing.T) {
+	now := time.Now().UnixMilli()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	app := New(context.Background(), configapp.AppConfig{}, aws.Config{}, 3600, logger)
+	app.rules = ruleSet{ignore: mustCompileRules("^INFO:")}
+	headers := map[string]string{"arn:a": "<h1>Log group: a</h1>\n", "arn:b": "<h1>Log group: b</h1>\n"}
+
+	events := make(chan tailEvent)
+	reports := make(chan string, 10)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		app.batchTailEvents(events, time.Hour, 200*time.Millisecond, func(batch map[string]map[string]*streamEvents) {
+			chLogLines := make(chan string, 100)
+			app.writeBatch(batch, headers, chLogLines)
+			close(chLogLines)
+			var report strings.Builder
+			for line := range chLogLines {
+				report.WriteString(line)
+			}
+			reports <- report.String()
+		})
+	}()
+	send := func(groupARN, message string) {
+		events <- tailEvent{groupARN: groupARN,
+			event: createLogEvent(now, "stream", "pod", "app:latest", "app", message)}
+	}
+
+	// A burst of lines is reported once, after the debounce delay
+	send("arn:a", "ERROR: first")
+	send("arn:a", "INFO: ignored")
+	send("arn:a", "ERROR: second")
+	var report string
+	select {
+	case report = <-reports:
+	case <-time.After(5 * time.Second):
+		t.Fatal("No report after the debounce delay")
+	}
+	if !strings.Contains(report, "ERROR: first") || !strings.Contains(report, "ERROR: second") ||
+		strings.Contains(report, "INFO: ignored") || !strings.HasPrefix(report, headers["arn:a"]) {
+		t.Errorf("Unexpected report:\n%s", report)
+	}
+
+	// The last batch is reported when the sessions end
+	send("arn:b", "INFO: ignored")
+	send("arn:b", "ERROR: third")
+	close(events)
+	<-done
+	close(reports)
+	var last []string
+	for report := range reports {
+		last = append(last, report)
+	}
+	if len(last) != 1 || !strings.HasPrefix(last[0], headers["arn:b"]) || strings.Contains(last[0], "first") ||
+		!strings.Contains(last[0], "ERROR: third") {
+		t.Errorf("Unexpected last reports %q", last)
+	}
+}
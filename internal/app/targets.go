@@ -0,0 +1,134 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/sgaunet/awslogcheck/internal/configapp"
+)
+
+// roleSessionName identifies the sessions of the assumed roles in CloudTrail.
+const roleSessionName = "awslogcheck"
+
+// STSAssumeRoleClient interface for testing.
+type STSAssumeRoleClient interface {
+	stscreds.AssumeRoleAPIClient
+	stscreds.AssumeRoleWithWebIdentityAPIClient
+}
+
+// logGroupCheck is a log group of a target to check.
+type logGroupCheck struct {
+	target    string // Name of the target in the report
+	client    *cloudwatchlogs.Client
+	groupName string
+	groupARN  string
+}
+
+// header returns the title of the log group in the report, empty if it is the only one of the checks.
+func (c logGroupCheck) header(checks int) string {
+	if checks <= 1 {
+		return ""
+	}
+	return logGroupHeader(c.title())
+}
+
+// title returns the name of the log group, prefixed by its target if any.
+func (c logGroupCheck) title() string {
+	if c.target != "" {
+		return c.target + ": " + c.groupName
+	}
+	return c.groupName
+}
+
+// logGroupHeader returns the header of a log group in the report.
+func logGroupHeader(title string) string {
+	return "<h1>Log group: " + html.EscapeString(title) + "</h1>\n"
+}
+
+// targetAWSConfig returns the AWS configuration of target: base with the region of the
+// target, and the credentials of its role.
+func targetAWSConfig(base aws.Config, target configapp.Target, stsClient STSAssumeRoleClient) (aws.Config, error) {
+	cfg := base.Copy()
+	if target.Region != "" {
+		cfg.Region = target.Region
+	}
+	switch {
+	case target.RoleARN == "":
+		if target.ExternalID != "" || target.WebIdentityTokenFile != "" {
+			return cfg, fmt.Errorf("%w: externalid and webidentitytokenfile need a rolearn", ErrInvalidTarget)
+		}
+	case target.WebIdentityTokenFile != "":
+		if target.ExternalID != "" {
+			return cfg, fmt.Errorf("%w: externalid can't be used with webidentitytokenfile", ErrInvalidTarget)
+		}
+		provider := stscreds.NewWebIdentityRoleProvider(stsClient, target.RoleARN,
+			stscreds.IdentityTokenFile(target.WebIdentityTokenFile), func(o *stscreds.WebIdentityRoleOptions) {
+				o.RoleSessionName = roleSessionName
+			})
+		cfg.Credentials = aws.NewCredentialsCache(provider)
+	default:
+		provider := stscreds.NewAssumeRoleProvider(stsClient, target.RoleARN, func(o *stscreds.AssumeRoleOptions) {
+			o.RoleSessionName = roleSessionName
+			if target.ExternalID != "" {
+				o.ExternalID = aws.String(target.ExternalID)
+			}
+		})
+		cfg.Credentials = aws.NewCredentialsCache(provider)
+	}
+	return cfg, nil
+}
+
+// targetName returns the name of target in the report.
+func targetName(target configapp.Target) string {
+	if target.Name != "" {
+		return target.Name
+	}
+	return strings.TrimSpace(target.RoleARN + " " + target.Region)
+}
+
+// targetError adds the name of target to err.
+func targetError(target configapp.Target, err error) error {
+	if name := targetName(target); name != "" {
+		return fmt.Errorf("target %s: %w", name, err)
+	}
+	return err
+}
+
+// resolveTargets returns the log groups to check of every target. A target failing is
+// skipped, and its error returned with the log groups of the other ones.
+func (a *App) resolveTargets(ctx context.Context) ([]logGroupCheck, []error) {
+	var checks []logGroupCheck
+	var errs []error
+	stsClient := a.newSTSClient(a.awscfg)
+	for _, target := range a.cfg.GetTargets() {
+		cfg, err := targetAWSConfig(a.awscfg, target, stsClient)
+		if err != nil {
+			errs = append(errs, targetError(target, err))
+			continue
+		}
+		client := a.newCloudWatchLogsClient(cfg)
+		selectors := target.GetLogGroupSelectors()
+		if len(selectors) == 0 {
+			selectors = a.cfg.GetLogGroupSelectors()
+		}
+		groups, err := a.resolveLogGroups(ctx, client, selectors)
+		if err != nil {
+			errs = append(errs, targetError(target, err))
+			continue
+		}
+		for _, group := range groups {
+			checks = append(checks, logGroupCheck{
+				target:    targetName(target),
+				client:    client,
+				groupName: aws.ToString(group.LogGroupName),
+				groupARN:  aws.ToString(group.LogGroupArn),
+			})
+		}
+	}
+	return checks, errs
+}
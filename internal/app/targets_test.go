@@ -0,0 +1,128 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	ststypes "github.com/aws/aws-sdk-go-v2/service/sts/types"
+	"github.com/sgaunet/awslogcheck/internal/configapp"
+)
+
+// mockSTSClient records the roles assumed.
+type mockSTSClient struct {
+	assumeRole     *sts.AssumeRoleInput
+	webIdentity    *sts.AssumeRoleWithWebIdentityInput
+	accessKeyID    string
+	expirationTime time.Time
+}
+
+func (m *mockSTSClient) credentials() *ststypes.Credentials {
+	return &ststypes.Credentials{
+		AccessKeyId:     aws.String(m.accessKeyID),
+		SecretAccessKey: aws.String("secret"),
+		SessionToken:    aws.String("token"),
+		Expiration:      aws.Time(m.expirationTime),
+	}
+}
+
+func (m *mockSTSClient) AssumeRole(_ context.Context, params *sts.AssumeRoleInput,
+	_ ...func(*sts.Options)) (*sts.AssumeRoleOutput, error) {
+	m.assumeRole = params
+	return &sts.AssumeRoleOutput{Credentials: m.credentials()}, nil
+}
+
+func (m *mockSTSClient) AssumeRoleWithWebIdentity(_ context.Context, params *sts.AssumeRoleWithWebIdentityInput,
+	_ ...func(*sts.Options)) (*sts.AssumeRoleWithWebIdentityOutput, error) {
+	m.webIdentity = params
+	return &sts.AssumeRoleWithWebIdentityOutput{Credentials: m.credentials()}, nil
+}
+
+func TestTargetAWSConfig(t *testing.T) {
+	base := aws.Config{
+		Region:      "eu-west-3",
+		Credentials: credentials.NewStaticCredentialsProvider("base", "secret", ""),
+	}
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("web-identity-token"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("region only", func(t *testing.T) {
+		cfg, err := targetAWSConfig(base, configapp.Target{Region: "us-east-1"}, &mockSTSClient{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		creds, _ := cfg.Credentials.Retrieve(context.Background())
+		if cfg.Region != "us-east-1" || creds.AccessKeyID != "base" || base.Region != "eu-west-3" {
+			t.Errorf("Unexpected configuration: region %s, key %s", cfg.Region, creds.AccessKeyID)
+		}
+	})
+
+	t.Run("assume role", func(t *testing.T) {
+		stsClient := &mockSTSClient{accessKeyID: "assumed", expirationTime: time.Now().Add(time.Hour)}
+		target := configapp.Target{RoleARN: "arn:aws:iam::111111111111:role/check", ExternalID: "external"}
+		cfg, err := targetAWSConfig(base, target, stsClient)
+		if err != nil {
+			t.Fatal(err)
+		}
+		creds, err := cfg.Credentials.Retrieve(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if creds.AccessKeyID != "assumed" || cfg.Region != "eu-west-3" {
+			t.Errorf("Unexpected configuration: region %s, key %s", cfg.Region, creds.AccessKeyID)
+		}
+		input := stsClient.assumeRole
+		if aws.ToString(input.RoleArn) != target.RoleARN || aws.ToString(input.ExternalId) != "external" ||
+			aws.ToString(input.RoleSessionName) != roleSessionName {
+			t.Errorf("Unexpected AssumeRole input %+v", input)
+		}
+	})
+
+	t.Run("web identity", func(t *testing.T) {
+		stsClient := &mockSTSClient{accessKeyID: "web", expirationTime: time.Now().Add(time.Hour)}
+		target := configapp.Target{RoleARN: "arn:aws:iam::222222222222:role/check", WebIdentityTokenFile: tokenFile}
+		cfg, err := targetAWSConfig(base, target, stsClient)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if creds, err := cfg.Credentials.Retrieve(context.Background()); err != nil || creds.AccessKeyID != "web" {
+			t.Fatalf("Unexpected credentials %v (%v)", creds, err)
+		}
+		if aws.ToString(stsClient.webIdentity.WebIdentityToken) != "web-identity-token" {
+			t.Errorf("Unexpected AssumeRoleWithWebIdentity input %+v", stsClient.webIdentity)
+		}
+	})
+
+	for name, target := range map[string]configapp.Target{
+		"external id without role":   {ExternalID: "external"},
+		"external id with web token": {RoleARN: "arn", ExternalID: "external", WebIdentityTokenFile: tokenFile},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := targetAWSConfig(base, target, &mockSTSClient{}); !errors.Is(err, ErrInvalidTarget) {
+				t.Errorf("Expected ErrInvalidTarget, got %v", err)
+			}
+		})
+	}
+}
+
+func TestTargetName(t *testing.T) {
+	tests := map[string]configapp.Target{
+		"":                   {},
+		"prod":               {Name: "prod", RoleARN: "arn", Region: "us-east-1"},
+		"arn:role eu-west-1": {RoleARN: "arn:role", Region: "eu-west-1"},
+		"us-east-1":          {Region: "us-east-1"},
+	}
+	for expected, target := range tests {
+		if name := targetName(target); name != expected {
+			t.Errorf("Expected %q, got %q", expected, name)
+		}
+	}
+}
@@ -12,17 +12,55 @@ import (
 // RULESDIR is the default directory name for rule files.
 const RULESDIR = "rules"
 
+// RulesFormatLogcheck is the rulesformat value for Debian logcheck rule directories.
+const RulesFormatLogcheck = "logcheck"
+
+// Values of ignorescope.
+const (
+	IgnoreScopeStream = "stream" // An ignored container hides its whole stream (default)
+	IgnoreScopeEvent  = "event"  // Only the events of the ignored containers are hidden
+)
+
+// Values of backend.
+const (
+	BackendFilter   = "filter"   // FilterLogEvents pagination (default)
+	BackendInsights = "insights" // Logs Insights queries
+)
+
 // AppConfig represents the application configuration.
 type AppConfig struct {
-	RulesDir              string            `yaml:"rulesdir"`
-	ImagesToIgnore        []string          `yaml:"imagesToIgnore"`
-	ContainerNameToIgnore []string          `yaml:"containerNameToIgnore"`
-	SMTPConfig            smtpConfig        `yaml:"smtp"`
-	MailgunConfig         MailGunConfig     `yaml:"mailgun"`
-	MailConfig            MailConfiguration `yaml:"mailconfiguration"`
-	AwsRegion             string            `yaml:"aws_region"`
-	LogGroup              string            `yaml:"loggroup"`
-	DebugLevel            string            `yaml:"debuglevel"`
+	RulesDir              string             `yaml:"rulesdir"`
+	RuleSources           []string           `yaml:"rulesources"`   // URLs of remote rules (s3://, https://, git+...)
+	RulesCacheDir         string             `yaml:"rulescachedir"` // Local copies of the remote rules
+	RulesFormat           string             `yaml:"rulesformat"`
+	LogcheckLevel         string             `yaml:"logchecklevel"`
+	RuleStats             RuleStatsConfig    `yaml:"rulestats"`
+	ReloadInterval        int                `yaml:"reloadinterval"` // Seconds between checks of the rules, <0 to disable
+	ImagesToIgnore        []string           `yaml:"imagesToIgnore"`
+	ContainerNameToIgnore []string           `yaml:"containerNameToIgnore"`
+	SelectorsToIgnore     []string           `yaml:"selectorsToIgnore"` // e.g. namespace=kube-system,labels.app=~^debug-
+	IgnoreScope           string             `yaml:"ignorescope"`
+	Multiline             MultilineConfig    `yaml:"multiline"`
+	ContextLines          int                `yaml:"contextlines"` // Ignored lines printed around the reported ones
+	SMTPConfig            smtpConfig         `yaml:"smtp"`
+	MailgunConfig         MailGunConfig      `yaml:"mailgun"`
+	MailConfig            MailConfiguration  `yaml:"mailconfiguration"`
+	AwsRegion             string             `yaml:"aws_region"`
+	Endpoints             EndpointsConfig    `yaml:"endpoints"`
+	SkipIdentityCheck     bool               `yaml:"skipidentitycheck"` // Don't print the AWS identity at startup
+	LogGroup              string             `yaml:"loggroup"`
+	LogGroups             []LogGroupSelector `yaml:"loggroups"`      // Log groups resolved at each run
+	Targets               []Target           `yaml:"targets"`        // Accounts and regions to check, the AWS configuration if empty
+	FilterPattern         string             `yaml:"filterpattern"`  // CloudWatch filter pattern, "auto" to derive it from the alert rules
+	FilterPatterns        map[string]string  `yaml:"filterpatterns"` // Filter pattern per log group
+	Backend               string             `yaml:"backend"`        // API used to fetch the events: filter or insights
+	Insights              InsightsConfig     `yaml:"insights"`
+	Fetch                 FetchConfig        `yaml:"fetch"`
+	Memory                MemoryConfig       `yaml:"memory"`
+	Retry                 RetryConfig        `yaml:"retry"`
+	Tail                  TailConfig         `yaml:"tail"`
+	Ingestion             IngestionConfig    `yaml:"ingestion"`
+	DebugLevel            string             `yaml:"debuglevel"`
 }
 
 // MailConfiguration contains email configuration settings.
@@ -39,6 +77,88 @@ type MailGunConfig struct {
 	APIKey string `yaml:"apikey"`
 }
 
+// RuleStatsConfig contains the settings of the rule hit statistics.
+type RuleStatsConfig struct {
+	Report      bool   `yaml:"report"`      // Append the hits of the run to the report
+	File        string `yaml:"file"`        // History of the hits, per day
+	HistoryDays int    `yaml:"historydays"` // Days kept in the history
+}
+
+// Target is an account and region to check.
+type Target struct {
+	Name                 string             `yaml:"name"`                 // Name in the report
+	RoleARN              string             `yaml:"rolearn"`              // Role assumed to check the target
+	ExternalID           string             `yaml:"externalid"`           // External ID of the role
+	WebIdentityTokenFile string             `yaml:"webidentitytokenfile"` // Token used to assume the role (EKS IRSA)
+	Region               string             `yaml:"region"`
+	LogGroup             string             `yaml:"loggroup"`
+	LogGroups            []LogGroupSelector `yaml:"loggroups"` // Log groups of the target, the ones of the configuration if empty
+}
+
+// EndpointsConfig contains the URLs of the AWS services, to use an emulator.
+// The endpoints of the SDK are used when empty.
+type EndpointsConfig struct {
+	CloudWatchLogs string `yaml:"cloudwatchlogs"`
+	STS            string `yaml:"sts"`
+	S3             string `yaml:"s3"` // Buckets are addressed by path
+}
+
+// LogGroupSelector selects log groups, all the fields set must match.
+type LogGroupSelector struct {
+	Name   string            `yaml:"name"`   // Exact name
+	Prefix string            `yaml:"prefix"` // Prefix of the name
+	Glob   string            `yaml:"glob"`   // Shell pattern, * does not match /
+	Regexp string            `yaml:"regexp"` // Regular expression
+	Tags   map[string]string `yaml:"tags"`   // Tags of the log group, an empty value matches any value
+}
+
+// FetchConfig contains the settings of the concurrent fetch of the events.
+type FetchConfig struct {
+	Workers int `yaml:"workers"` // Concurrent FilterLogEvents paginations, <=1 to fetch sequentially
+	Slices  int `yaml:"slices"`  // Sub-ranges of the window, at least workers
+}
+
+// MemoryConfig contains the limits of the events kept in memory until the report is built.
+type MemoryConfig struct {
+	MaxStreamEvents int    `yaml:"maxstreamevents"` // Events of a stream, spilled to disk beyond
+	MaxEvents       int    `yaml:"maxevents"`       // Events of all the streams, the largest are spilled beyond
+	SpillDir        string `yaml:"spilldir"`        // Directory of the spill files, the temporary directory if empty
+}
+
+// RetryConfig contains the settings of the retries of the AWS API calls.
+type RetryConfig struct {
+	MaxAttempts int `yaml:"maxattempts"` // Attempts of a call, retries included
+	BaseDelay   int `yaml:"basedelay"`   // Milliseconds, maximum delay before the first retry
+	MaxDelay    int `yaml:"maxdelay"`    // Milliseconds, maximum delay between two attempts
+}
+
+// TailConfig contains the settings of the live tail mode.
+type TailConfig struct {
+	Interval int `yaml:"interval"` // Seconds, maximum delay of a notification
+	Debounce int `yaml:"debounce"` // Seconds without new line to report before a notification is sent
+}
+
+// IngestionConfig contains the settings of the wait for the events of the window to be ingested.
+type IngestionConfig struct {
+	MaxWait      int `yaml:"maxwait"`      // Seconds, maximum wait before checking, <0 to not wait
+	PollInterval int `yaml:"pollinterval"` // Seconds between two checks of the log streams
+	Quiet        int `yaml:"quiet"`        // Seconds without ingestion after which a stream is idle
+}
+
+// InsightsConfig contains the settings of the Logs Insights backend.
+type InsightsConfig struct {
+	Query        string `yaml:"query"`        // Query template, must return @timestamp, @message and @logStream
+	Limit        int32  `yaml:"limit"`        // Maximum results of a query, the window is split beyond
+	PollInterval int    `yaml:"pollinterval"` // Milliseconds between polls of the results
+}
+
+// MultilineConfig contains the settings of the assembly of multi-line events (stack traces).
+type MultilineConfig struct {
+	StartPattern string   `yaml:"startpattern"` // Regexp matching the first line of an event
+	Detectors    []string `yaml:"detectors"`    // Built-in detectors: java, python, go
+	MaxLines     int      `yaml:"maxlines"`     // Maximum lines of an event
+}
+
 type smtpConfig struct {
 	Server        string `yaml:"server"`
 	Port          int    `yaml:"port"`
@@ -77,6 +197,69 @@ func (a *AppConfig) IsSMTPConfigured() bool {
 	return a.SMTPConfig.Login != "" && a.SMTPConfig.Port != 0 && a.SMTPConfig.Password != "" && a.SMTPConfig.Server != ""
 }
 
+// IsLogcheckFormat checks if the rules directory uses the Debian logcheck layout.
+func (a *AppConfig) IsLogcheckFormat() bool {
+	return a.RulesFormat == RulesFormatLogcheck
+}
+
+// IsIgnoredPerEvent checks if ignored images and containers only hide their own events,
+// instead of the whole stream.
+func (a *AppConfig) IsIgnoredPerEvent() bool {
+	return a.IgnoreScope == IgnoreScopeEvent
+}
+
+// IsInsightsBackend checks if the events are fetched with Logs Insights queries.
+func (a *AppConfig) IsInsightsBackend() bool {
+	return a.Backend == BackendInsights
+}
+
+// HasRuleSources checks if remote rule sources are configured.
+func (a *AppConfig) HasRuleSources() bool {
+	return len(a.RuleSources) > 0
+}
+
+// GetLogGroupSelectors returns the selectors of the log groups to check, loggroup included.
+func (a *AppConfig) GetLogGroupSelectors() []LogGroupSelector {
+	selectors := make([]LogGroupSelector, 0, len(a.LogGroups)+1)
+	if a.LogGroup != "" {
+		selectors = append(selectors, LogGroupSelector{Name: a.LogGroup})
+	}
+	return append(selectors, a.LogGroups...)
+}
+
+// GetTargets returns the targets to check: a target using the AWS configuration if none is configured.
+func (a *AppConfig) GetTargets() []Target {
+	if len(a.Targets) == 0 {
+		return []Target{{}}
+	}
+	return a.Targets
+}
+
+// GetLogGroupSelectors returns the selectors of the log groups of the target, loggroup included.
+func (t *Target) GetLogGroupSelectors() []LogGroupSelector {
+	selectors := make([]LogGroupSelector, 0, len(t.LogGroups)+1)
+	if t.LogGroup != "" {
+		selectors = append(selectors, LogGroupSelector{Name: t.LogGroup})
+	}
+	return append(selectors, t.LogGroups...)
+}
+
+// GetFilterPattern returns the CloudWatch filter pattern of the log group, empty to fetch every event.
+func (a *AppConfig) GetFilterPattern(groupName string) string {
+	if pattern, ok := a.FilterPatterns[groupName]; ok {
+		return pattern
+	}
+	return a.FilterPattern
+}
+
+// GetRulesCacheDir returns the directory of the local copies of the remote rule sources.
+func (a *AppConfig) GetRulesCacheDir() string {
+	if a.RulesCacheDir != "" {
+		return a.RulesCacheDir
+	}
+	return filepath.Join(os.TempDir(), "awslogcheck-rules")
+}
+
 // GetRulesDir returns path of rules directory.
 // If empty, return the path of the binary/rules.
 func (a *AppConfig) GetRulesDir() (string, error) {
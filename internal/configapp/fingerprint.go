@@ -0,0 +1,80 @@
+package configapp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/sgaunet/awslogcheck/internal/rules"
+)
+
+// Fingerprint returns a hash of the configuration file, of the files of its
+// rules directory and of the files they include, used to detect changes.
+// Contents are hashed, not modification times, so the symlink swap done by
+// Kubernetes when a ConfigMap volume is updated is detected.
+func Fingerprint(configFilename string) (string, error) {
+	hash := sha256.New()
+	if err := hashFile(hash, configFilename); err != nil {
+		return "", err
+	}
+	config, err := ReadYamlCnxFile(configFilename)
+	if err != nil {
+		return "", err
+	}
+	rulesDir, err := config.GetRulesDir()
+	if errors.Is(err, ErrRulesDirNotFound) && config.HasRuleSources() {
+		// Remote sources are refreshed before each run, not watched
+		return hex.EncodeToString(hash.Sum(nil)), nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	err = filepath.Walk(rulesDir, func(pathitem string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if pathitem != rulesDir && rules.IsIgnoredFile(info.Name()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() {
+			return nil
+		}
+		_, _ = io.WriteString(hash, pathitem+"\x00")
+		return hashFile(hash, pathitem)
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to walk rules directory: %w", err)
+	}
+	// Included files may be outside of the rules directory. If the rules can't be
+	// loaded, the reload triggered by the change of the walked files reports it.
+	if loaded, err := rules.Files(rulesDir); err == nil {
+		for _, filename := range loaded {
+			_, _ = io.WriteString(hash, filename+"\x00")
+			if err := hashFile(hash, filename); err != nil {
+				return "", err
+			}
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func hashFile(w io.Writer, filename string) error {
+	// #nosec G304 - filename is the configuration file or a rule file
+	f, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", filename, err)
+	}
+	defer func() { _ = f.Close() }()
+	if _, err := io.Copy(w, f); err != nil {
+		return fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package configapp_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sgaunet/awslogcheck/internal/configapp"
+)
+
+// TestFingerprintConfigMapUpdate simulates the update of a Kubernetes ConfigMap volume:
+// files are symlinks to ..data, itself a symlink swapped to a new directory.
+func TestFingerprintConfigMapUpdate(t *testing.T) {
+	rulesDir := t.TempDir()
+	writeVersion := func(version string, content string) {
+		dir := filepath.Join(rulesDir, version)
+		if err := os.Mkdir(dir, 0o755); err != nil {
+			t.Fatalf("Setup failed: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "app.rule"), []byte(content), 0o644); err != nil {
+			t.Fatalf("Setup failed: %v", err)
+		}
+		tmpLink := filepath.Join(rulesDir, "..data_tmp")
+		if err := os.Symlink(version, tmpLink); err != nil {
+			t.Fatalf("Setup failed: %v", err)
+		}
+		if err := os.Rename(tmpLink, filepath.Join(rulesDir, "..data")); err != nil {
+			t.Fatalf("Setup failed: %v", err)
+		}
+	}
+	writeVersion("..2024_01_01", "^INFO:\n")
+	if err := os.Symlink(filepath.Join("..data", "app.rule"), filepath.Join(rulesDir, "app.rule")); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+
+	configFile := filepath.Join(t.TempDir(), "cfg.yaml")
+	if err := os.WriteFile(configFile, []byte("rulesdir: "+rulesDir+"\n"), 0o644); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+
+	first, err := configapp.Fingerprint(configFile)
+	if err != nil {
+		t.Fatalf("Fingerprint returned error: %v", err)
+	}
+	again, err := configapp.Fingerprint(configFile)
+	if err != nil {
+		t.Fatalf("Fingerprint returned error: %v", err)
+	}
+	if first != again {
+		t.Error("Fingerprint should be stable")
+	}
+
+	writeVersion("..2024_01_02", "^INFO:\n^DEBUG:\n")
+	updated, err := configapp.Fingerprint(configFile)
+	if err != nil {
+		t.Fatalf("Fingerprint returned error: %v", err)
+	}
+	if updated == first {
+		t.Error("Fingerprint should change when the ConfigMap is updated")
+	}
+
+	if err := os.WriteFile(configFile, []byte("rulesdir: "+rulesDir+"\ndebuglevel: debug\n"), 0o644); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	configChanged, err := configapp.Fingerprint(configFile)
+	if err != nil {
+		t.Fatalf("Fingerprint returned error: %v", err)
+	}
+	if configChanged == updated {
+		t.Error("Fingerprint should change when the configuration is updated")
+	}
+}
+
+// TestFingerprintIncludedFile tests the change of a file included from outside of the rules directory.
+func TestFingerprintIncludedFile(t *testing.T) {
+	rulesDir := t.TempDir()
+	common := filepath.Join(t.TempDir(), "common.rule")
+	if err := os.WriteFile(common, []byte("^INFO:\n"), 0o644); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(rulesDir, "app.rule"), []byte("#!include "+common+"\n"), 0o644); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	configFile := filepath.Join(t.TempDir(), "cfg.yaml")
+	if err := os.WriteFile(configFile, []byte("rulesdir: "+rulesDir+"\n"), 0o644); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+
+	first, err := configapp.Fingerprint(configFile)
+	if err != nil {
+		t.Fatalf("Fingerprint returned error: %v", err)
+	}
+	if err := os.WriteFile(common, []byte("^INFO:\n^DEBUG:\n"), 0o644); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	updated, err := configapp.Fingerprint(configFile)
+	if err != nil {
+		t.Fatalf("Fingerprint returned error: %v", err)
+	}
+	if updated == first {
+		t.Error("Fingerprint should change when an included file is updated")
+	}
+}
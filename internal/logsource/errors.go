@@ -0,0 +1,10 @@
+package logsource
+
+import "errors"
+
+// Static errors for wrapping.
+var (
+	ErrUnsupportedFormat = errors.New("unsupported log format")
+	ErrUnsupportedSource = errors.New("unsupported log source")
+	ErrNoS3Client        = errors.New("no S3 client")
+)
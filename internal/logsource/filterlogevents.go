@@ -0,0 +1,41 @@
+package logsource
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+// filterLogEventsOutput is the output of aws logs filter-log-events.
+type filterLogEventsOutput struct {
+	Events []jsonEvent `json:"events"`
+}
+
+// parseFilterLogEvents reads the output of aws logs filter-log-events: one document, or several
+// ones concatenated when the pages were saved one after another.
+func parseFilterLogEvents(r io.Reader, stream string, fn func([]types.FilteredLogEvent) error) error {
+	p := pager{fn: fn}
+	decoder := json.NewDecoder(r)
+	for {
+		var output filterLogEventsOutput
+		err := decoder.Decode(&output)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to decode filter-log-events output: %w", err)
+		}
+		for _, e := range output.Events {
+			if e.Message == nil {
+				continue
+			}
+			if err := p.add(e.toFilteredLogEvent(stream)); err != nil {
+				return err
+			}
+		}
+	}
+	return p.flush()
+}
@@ -0,0 +1,58 @@
+package logsource
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+// jsonEvent is an event of a JSON-lines file, or of the output of filter-log-events.
+type jsonEvent struct {
+	EventID       string  `json:"eventId"`
+	LogStreamName string  `json:"logStreamName"`
+	Message       *string `json:"message"`
+	Timestamp     int64   `json:"timestamp"`
+}
+
+// toFilteredLogEvent returns the event, in the stream given if it has none.
+func (e jsonEvent) toFilteredLogEvent(stream string) types.FilteredLogEvent {
+	if e.LogStreamName != "" {
+		stream = e.LogStreamName
+	}
+	event := types.FilteredLogEvent{
+		LogStreamName: aws.String(stream),
+		Message:       e.Message,
+		Timestamp:     aws.Int64(e.Timestamp),
+	}
+	if e.EventID != "" {
+		event.EventId = aws.String(e.EventID)
+	}
+	return event
+}
+
+// parseJSONLines reads one event per line. A line which is not an object with a message is
+// the message of an event itself, as written by fluent-bit to a file.
+func parseJSONLines(r io.Reader, stream string, fn func([]types.FilteredLogEvent) error) error {
+	p := pager{fn: fn}
+	scanner := newScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var e jsonEvent
+		if err := json.Unmarshal(line, &e); err != nil || e.Message == nil {
+			e = jsonEvent{Message: aws.String(string(line))}
+		}
+		if err := p.add(e.toFilteredLogEvent(stream)); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read JSON lines: %w", err)
+	}
+	return p.flush()
+}
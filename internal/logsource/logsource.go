@@ -0,0 +1,201 @@
+// Package logsource reads log events from other sources than the FilterLogEvents API, to
+// check archived or captured logs.
+//
+// Supported inputs:
+//
+//	path/to/file        a local file
+//	-                   the standard input
+//	s3://bucket/prefix  a CloudWatch Logs export to S3 (gzipped objects)
+//
+// Supported formats of the files:
+//
+//	jsonl               one event per line: {"timestamp":..., "message":"...", "logStreamName":"..."}
+//	filter-log-events   output of aws logs filter-log-events
+//	export              object of a CloudWatch Logs export to S3, gzipped
+//	text                plain text, one message per line
+//
+// The messages are the records of fluentd/fluent-bit, like in CloudWatch Logs: the lines of
+// text are converted to records without kubernetes metadata.
+package logsource
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+// Formats of the files.
+const (
+	FormatJSONLines       = "jsonl"
+	FormatFilterLogEvents = "filter-log-events"
+	FormatExport          = "export"
+	FormatText            = "text"
+)
+
+const (
+	stdinInput = "-"
+	s3Prefix   = "s3://"
+
+	pageSize     = 1000    // Events passed at once to the callback of Read
+	maxLineBytes = 1 << 20 // Longest line, CloudWatch Logs events are 256 KB at most
+)
+
+// Source is a source of log events.
+type Source interface {
+	// Name returns the name of the source in the report.
+	Name() string
+	// Read calls fn with the events of the source, by pages.
+	Read(ctx context.Context, fn func([]types.FilteredLogEvent) error) error
+}
+
+// Options contains the settings shared by the sources.
+type Options struct {
+	Format string    // Format of the files, from their extension if empty
+	S3     S3API     // Client for s3:// inputs
+	Stdin  io.Reader // Reader of the "-" input, os.Stdin if nil
+}
+
+// parser reads the events of r, stream being the stream of the events without one.
+type parser func(r io.Reader, stream string, fn func([]types.FilteredLogEvent) error) error
+
+// readerSource is a file, or the standard input.
+type readerSource struct {
+	name   string
+	stream string // Stream of the events without one
+	open   func() (io.ReadCloser, error)
+	parse  parser
+}
+
+// New returns the source of input.
+func New(input string, opts Options) (Source, error) {
+	if strings.HasPrefix(input, s3Prefix) {
+		return newS3ExportSource(input, opts)
+	}
+	if input == "" {
+		return nil, fmt.Errorf("%w: empty input", ErrUnsupportedSource)
+	}
+	format := opts.Format
+	if format == "" {
+		format = formatOf(input)
+	}
+	parse, err := parserOf(format)
+	if err != nil {
+		return nil, err
+	}
+
+	if input == stdinInput {
+		stdin := opts.Stdin
+		if stdin == nil {
+			stdin = os.Stdin
+		}
+		return &readerSource{
+			name:   "stdin",
+			stream: "stdin",
+			open:   func() (io.ReadCloser, error) { return io.NopCloser(stdin), nil },
+			parse:  parse,
+		}, nil
+	}
+	return &readerSource{
+		name:   input,
+		stream: streamOfFile(input, format),
+		open: func() (io.ReadCloser, error) {
+			// #nosec G304 - the file to check is given on the command line
+			f, err := os.Open(input)
+			if err != nil {
+				return nil, fmt.Errorf("failed to open %s: %w", input, err)
+			}
+			return f, nil
+		},
+		parse: parse,
+	}, nil
+}
+
+// formatOf returns the format of a file from its extension: text by default.
+func formatOf(input string) string {
+	switch strings.ToLower(filepath.Ext(input)) {
+	case ".jsonl", ".ndjson":
+		return FormatJSONLines
+	case ".json":
+		return FormatFilterLogEvents
+	case ".gz":
+		return FormatExport
+	default:
+		return FormatText
+	}
+}
+
+// parserOf returns the parser of format.
+func parserOf(format string) (parser, error) {
+	switch format {
+	case FormatJSONLines:
+		return parseJSONLines, nil
+	case FormatFilterLogEvents:
+		return parseFilterLogEvents, nil
+	case FormatExport:
+		return parseExport, nil
+	case FormatText:
+		return parseText, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+	}
+}
+
+// streamOfFile returns the stream of the events of a file without one: the directory of an
+// export object (named after its stream), the name of the file otherwise.
+func streamOfFile(input, format string) string {
+	if format == FormatExport {
+		if dir := filepath.Base(filepath.Dir(input)); dir != "." && dir != string(filepath.Separator) {
+			return dir
+		}
+	}
+	return filepath.Base(input)
+}
+
+// Name returns the name of the source in the report.
+func (s *readerSource) Name() string { return s.name }
+
+// Read calls fn with the events of the file, by pages.
+func (s *readerSource) Read(_ context.Context, fn func([]types.FilteredLogEvent) error) error {
+	r, err := s.open()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = r.Close() }()
+	return s.parse(r, s.stream, fn)
+}
+
+// pager passes the events to fn by pages.
+type pager struct {
+	fn     func([]types.FilteredLogEvent) error
+	events []types.FilteredLogEvent
+}
+
+func (p *pager) add(event types.FilteredLogEvent) error {
+	p.events = append(p.events, event)
+	if len(p.events) < pageSize {
+		return nil
+	}
+	return p.flush()
+}
+
+func (p *pager) flush() error {
+	if len(p.events) == 0 {
+		return nil
+	}
+	err := p.fn(p.events)
+	p.events = nil
+	return err
+}
+
+// newScanner returns a scanner of the lines of r, up to maxLineBytes.
+func newScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxLineBytes)
+	return scanner
+}
@@ -0,0 +1,236 @@
+package logsource_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	cwtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/sgaunet/awslogcheck/internal/logsource"
+)
+
+// event is the part of an event checked by the tests.
+type event struct {
+	stream    string
+	message   string
+	timestamp int64
+}
+
+// readAll returns the events of a source.
+func readAll(t *testing.T, source logsource.Source) []event {
+	t.Helper()
+	var events []event
+	err := source.Read(context.Background(), func(page []cwtypes.FilteredLogEvent) error {
+		for _, e := range page {
+			events = append(events, event{
+				stream:    aws.ToString(e.LogStreamName),
+				message:   aws.ToString(e.Message),
+				timestamp: aws.ToInt64(e.Timestamp),
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Read returned error: %v", err)
+	}
+	return events
+}
+
+// writeFile writes content to name in a temporary directory, and returns its path.
+func writeFile(t *testing.T, name string, content []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// gzipped returns content compressed.
+func gzipped(t *testing.T, content string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// TestFileSources tests the formats of the files, guessed from their extension
+func TestFileSources(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		content  []byte
+		expected []event
+	}{
+		{
+			name: "JSON lines",
+			file: "captured.jsonl",
+			content: []byte(`{"timestamp":1760770800000,"message":"{\"log\":\"ERROR: a\"}","logStreamName":"api"}` + "\n" +
+				"\n" +
+				`{"log":"ERROR: b"}` + "\n"),
+			expected: []event{
+				{stream: "api", message: `{"log":"ERROR: a"}`, timestamp: 1760770800000},
+				{stream: "captured.jsonl", message: `{"log":"ERROR: b"}`},
+			},
+		},
+		{
+			name: "filter-log-events",
+			file: "events.json",
+			content: []byte(`{"events":[{"logStreamName":"api","timestamp":1760770800000,` +
+				`"message":"{\"log\":\"ERROR: a\"}","eventId":"1"}],"searchedLogStreams":[]}` +
+				`{"events":[{"logStreamName":"web","timestamp":1760770801000,"message":"{\"log\":\"ERROR: b\"}"}]}`),
+			expected: []event{
+				{stream: "api", message: `{"log":"ERROR: a"}`, timestamp: 1760770800000},
+				{stream: "web", message: `{"log":"ERROR: b"}`, timestamp: 1760770801000},
+			},
+		},
+		{
+			name: "export",
+			file: "api-7d9f8b6c5-x2x4q/000000.gz",
+			content: gzipped(t, `2025-10-18T07:00:00.000Z {"log":"ERROR: a"}`+"\n"+
+				`2025-10-18T07:00:01.500Z {"log":"ERROR: b`+"\n"+
+				`  at main.go:12"}`+"\n"),
+			expected: []event{
+				{stream: "api-7d9f8b6c5-x2x4q", message: `{"log":"ERROR: a"}`, timestamp: 1760770800000},
+				{stream: "api-7d9f8b6c5-x2x4q", message: "{\"log\":\"ERROR: b\n  at main.go:12\"}", timestamp: 1760770801500},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, err := logsource.New(writeFile(t, tt.file, tt.content), logsource.Options{})
+			if err != nil {
+				t.Fatalf("New returned error: %v", err)
+			}
+			if got := readAll(t, source); !slices.Equal(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestStdinSource tests the lines of text read from the standard input
+func TestStdinSource(t *testing.T) {
+	stdin := strings.NewReader("ERROR: \"quoted\"\n\nWARN: b\n")
+	source, err := logsource.New("-", logsource.Options{Stdin: stdin})
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	events := readAll(t, source)
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %v", events)
+	}
+	var record struct {
+		Log string `json:"log"`
+	}
+	if err := json.Unmarshal([]byte(events[0].message), &record); err != nil {
+		t.Fatalf("Message is not a fluentd record: %v", err)
+	}
+	if record.Log != `ERROR: "quoted"` || events[0].stream != "stdin" || events[0].timestamp == 0 {
+		t.Errorf("Unexpected event: %v", events[0])
+	}
+}
+
+// TestPages tests that the events are passed by pages of 1000
+func TestPages(t *testing.T) {
+	stdin := strings.NewReader(strings.Repeat("ERROR: a\n", 2500))
+	source, err := logsource.New("-", logsource.Options{Stdin: stdin})
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	var sizes []int
+	err = source.Read(context.Background(), func(page []cwtypes.FilteredLogEvent) error {
+		sizes = append(sizes, len(page))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Read returned error: %v", err)
+	}
+	if !slices.Equal(sizes, []int{1000, 1000, 500}) {
+		t.Errorf("Unexpected pages: %v", sizes)
+	}
+}
+
+// mockS3 is an in-memory bucket.
+type mockS3 struct {
+	objects map[string][]byte
+}
+
+func (m *mockS3) ListObjectsV2(_ context.Context, params *s3.ListObjectsV2Input,
+	_ ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	output := &s3.ListObjectsV2Output{}
+	for key := range m.objects {
+		if strings.HasPrefix(key, aws.ToString(params.Prefix)) {
+			output.Contents = append(output.Contents, types.Object{Key: aws.String(key)})
+		}
+	}
+	slices.SortFunc(output.Contents, func(a, b types.Object) int { return strings.Compare(*a.Key, *b.Key) })
+	return output, nil
+}
+
+func (m *mockS3) GetObject(_ context.Context, params *s3.GetObjectInput,
+	_ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(m.objects[aws.ToString(params.Key)]))}, nil
+}
+
+// TestS3ExportSource tests the objects of an export to S3
+func TestS3ExportSource(t *testing.T) {
+	bucket := &mockS3{objects: map[string][]byte{
+		"exports/aws-logs-write-test":  []byte("Permission Check Successful"),
+		"exports/task/api/000000.gz":   gzipped(t, `2025-10-18T07:00:00Z {"log":"ERROR: a"}`+"\n"),
+		"exports/task/web/000000.gz":   gzipped(t, `2025-10-18T07:00:01Z {"log":"ERROR: b"}`+"\n"),
+		"other/task/ignored/000000.gz": gzipped(t, `2025-10-18T07:00:02Z {"log":"ERROR: c"}`+"\n"),
+	}}
+	source, err := logsource.New("s3://bucket/exports/task", logsource.Options{S3: bucket})
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	expected := []event{
+		{stream: "api", message: `{"log":"ERROR: a"}`, timestamp: 1760770800000},
+		{stream: "web", message: `{"log":"ERROR: b"}`, timestamp: 1760770801000},
+	}
+	if got := readAll(t, source); !slices.Equal(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+// TestNewErrors tests the invalid sources
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		opts     logsource.Options
+		expected error
+	}{
+		{input: "", expected: logsource.ErrUnsupportedSource},
+		{input: "-", opts: logsource.Options{Format: "csv"}, expected: logsource.ErrUnsupportedFormat},
+		{input: "s3://bucket/exports", expected: logsource.ErrNoS3Client},
+		{input: "s3:///exports", opts: logsource.Options{S3: &mockS3{}}, expected: logsource.ErrUnsupportedSource},
+		{input: "s3://bucket/exports", opts: logsource.Options{S3: &mockS3{}, Format: "text"},
+			expected: logsource.ErrUnsupportedFormat},
+	}
+	for _, tt := range tests {
+		if _, err := logsource.New(tt.input, tt.opts); !errors.Is(err, tt.expected) {
+			t.Errorf("New(%q): expected %v, got %v", tt.input, tt.expected, err)
+		}
+	}
+}
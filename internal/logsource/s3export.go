@@ -0,0 +1,141 @@
+package logsource
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// S3API is the part of the S3 client used to read s3:// exports.
+type S3API interface {
+	s3.ListObjectsV2APIClient
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+}
+
+// s3ExportSource is the set of objects of a CloudWatch Logs export under an S3 prefix.
+type s3ExportSource struct {
+	rawURL string
+	client S3API
+	bucket string
+	prefix string
+}
+
+func newS3ExportSource(rawURL string, opts Options) (*s3ExportSource, error) {
+	if opts.S3 == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoS3Client, rawURL)
+	}
+	if opts.Format != "" && opts.Format != FormatExport {
+		return nil, fmt.Errorf("%w: %s for %s", ErrUnsupportedFormat, opts.Format, rawURL)
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", rawURL, err)
+	}
+	if parsed.Host == "" {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedSource, rawURL)
+	}
+	return &s3ExportSource{
+		rawURL: rawURL,
+		client: opts.S3,
+		bucket: parsed.Host,
+		prefix: strings.TrimPrefix(parsed.Path, "/"),
+	}, nil
+}
+
+// Name returns the name of the source in the report.
+func (s *s3ExportSource) Name() string { return s.rawURL }
+
+// Read calls fn with the events of the objects, in the order of their keys. The stream of the
+// events is the parent "directory" of their object, named after the stream by the export.
+func (s *s3ExportSource) Read(ctx context.Context, fn func([]types.FilteredLogEvent) error) error {
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(s.prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list objects of %s: %w", s.rawURL, err)
+		}
+		for _, object := range page.Contents {
+			key := aws.ToString(object.Key)
+			if !strings.HasSuffix(key, ".gz") {
+				continue // Folder placeholder, or aws-logs-write-test
+			}
+			if err := s.readObject(ctx, key, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readObject calls fn with the events of an object.
+func (s *s3ExportSource) readObject(ctx context.Context, key string,
+	fn func([]types.FilteredLogEvent) error) error {
+	output, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get s3://%s/%s: %w", s.bucket, key, err)
+	}
+	defer func() { _ = output.Body.Close() }()
+	if err := parseExport(output.Body, path.Base(path.Dir(key)), fn); err != nil {
+		return fmt.Errorf("failed to read s3://%s/%s: %w", s.bucket, key, err)
+	}
+	return nil
+}
+
+// parseExport reads a gzipped object of an export: one event per line, prefixed by its
+// timestamp. A line without a timestamp continues the message of the previous one.
+func parseExport(r io.Reader, stream string, fn func([]types.FilteredLogEvent) error) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("failed to decompress export: %w", err)
+	}
+	defer func() { _ = gz.Close() }()
+
+	p := pager{fn: fn}
+	var pending *types.FilteredLogEvent
+	scanner := newScanner(gz)
+	for scanner.Scan() {
+		line := scanner.Text()
+		timestamp, message, found := strings.Cut(line, " ")
+		eventTime, err := time.Parse(time.RFC3339Nano, timestamp)
+		if !found || err != nil {
+			if pending != nil {
+				pending.Message = aws.String(aws.ToString(pending.Message) + "\n" + line)
+			}
+			continue
+		}
+		if pending != nil {
+			if err := p.add(*pending); err != nil {
+				return err
+			}
+		}
+		pending = &types.FilteredLogEvent{
+			LogStreamName: aws.String(stream),
+			Message:       aws.String(message),
+			Timestamp:     aws.Int64(eventTime.UnixMilli()),
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read export: %w", err)
+	}
+	if pending != nil {
+		if err := p.add(*pending); err != nil {
+			return err
+		}
+	}
+	return p.flush()
+}
@@ -0,0 +1,44 @@
+package logsource
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+// textRecord is the fluentd record of a line of text.
+type textRecord struct {
+	Log string `json:"log"`
+}
+
+// parseText reads one message per line, timestamped when read.
+func parseText(r io.Reader, stream string, fn func([]types.FilteredLogEvent) error) error {
+	p := pager{fn: fn}
+	scanner := newScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		record, err := json.Marshal(textRecord{Log: line})
+		if err != nil {
+			return fmt.Errorf("failed to convert line of text: %w", err)
+		}
+		event := types.FilteredLogEvent{
+			LogStreamName: aws.String(stream),
+			Message:       aws.String(string(record)),
+			Timestamp:     aws.Int64(time.Now().UnixMilli()),
+		}
+		if err := p.add(event); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read text: %w", err)
+	}
+	return p.flush()
+}
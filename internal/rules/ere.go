@@ -0,0 +1,116 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+)
+
+// BSD word boundaries, still found in some logcheck rules.
+var bsdWordBoundaries = []string{"[[:<:]]", "[[:>:]]"}
+
+// TranslateERE converts a POSIX extended regular expression, as used by egrep
+// and logcheck, to the RE2 syntax of the regexp package.
+// Constructs without RE2 equivalent (backreferences, collating elements...)
+// return an ErrUntranslatable error, and malformed patterns an ErrInvalidERE error.
+func TranslateERE(pattern string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		if boundary := hasAnyPrefix(pattern[i:], bsdWordBoundaries); boundary != "" {
+			b.WriteString(`\b`)
+			i += len(boundary) - 1
+			continue
+		}
+		c := pattern[i]
+		switch {
+		case c == '[':
+			end, class, err := translateBracket(pattern, i)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(class)
+			i = end
+		case c == '\\' && i+1 < len(pattern):
+			next := pattern[i+1]
+			switch {
+			case next == '<' || next == '>':
+				b.WriteString(`\b`)
+			case next >= '1' && next <= '9':
+				return "", fmt.Errorf("%w: backreference \\%c", ErrUntranslatable, next)
+			default:
+				b.WriteByte(c)
+				b.WriteByte(next)
+			}
+			i++
+		case c == '{' && strings.HasPrefix(pattern[i:], "{,"):
+			// GNU extension: {,n} means {0,n}, RE2 would read it literally
+			b.WriteString("{0,")
+			i++
+		default:
+			b.WriteByte(c)
+		}
+	}
+	translated := b.String()
+	if _, err := regexp.Compile(translated); err != nil {
+		// Repeat counts are limited to 1000 by RE2, not by egrep
+		var syntaxErr *syntax.Error
+		if errors.As(err, &syntaxErr) && syntaxErr.Code == syntax.ErrInvalidRepeatSize {
+			return "", fmt.Errorf("%w: %w", ErrUntranslatable, err)
+		}
+		return "", fmt.Errorf("%w: %w", ErrInvalidERE, err)
+	}
+	return translated, nil
+}
+
+// translateBracket translates the bracket expression starting at pattern[start].
+// It returns the index of the closing bracket and the RE2 character class.
+func translateBracket(pattern string, start int) (int, string, error) {
+	var b strings.Builder
+	b.WriteByte('[')
+	i := start + 1
+	if i < len(pattern) && pattern[i] == '^' {
+		b.WriteByte('^')
+		i++
+	}
+	// A closing bracket right after the opening one is a literal
+	if i < len(pattern) && pattern[i] == ']' {
+		b.WriteString(`\]`)
+		i++
+	}
+	for ; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == ']':
+			b.WriteByte(']')
+			return i, b.String(), nil
+		case c == '[' && strings.HasPrefix(pattern[i:], "[:"):
+			end := strings.Index(pattern[i+2:], ":]")
+			if end < 0 {
+				return 0, "", fmt.Errorf("%w: unterminated character class", ErrInvalidERE)
+			}
+			b.WriteString(pattern[i : i+2+end+2])
+			i += 2 + end + 1
+		case c == '[' && (strings.HasPrefix(pattern[i:], "[=") || strings.HasPrefix(pattern[i:], "[.")):
+			return 0, "", fmt.Errorf("%w: collating element %s", ErrUntranslatable, pattern[i:i+2])
+		case c == '\\':
+			// Backslashes are literals inside POSIX bracket expressions
+			b.WriteString(`\\`)
+		case c == '[':
+			b.WriteString(`\[`)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return 0, "", fmt.Errorf("%w: unterminated bracket expression", ErrInvalidERE)
+}
+
+func hasAnyPrefix(s string, prefixes []string) string {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return prefix
+		}
+	}
+	return ""
+}
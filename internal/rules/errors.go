@@ -0,0 +1,15 @@
+package rules
+
+import "errors"
+
+// Static errors for wrapping.
+var (
+	ErrIncludeCycle     = errors.New("include cycle detected")
+	ErrIncludeNotFound  = errors.New("included rule file not found")
+	ErrInvalidDirective = errors.New("invalid directive")
+	ErrUntranslatable   = errors.New("rule cannot be translated to RE2")
+	ErrInvalidERE       = errors.New("invalid extended regular expression")
+	ErrInvalidFieldRule = errors.New("invalid field rule")
+
+	ErrInvalidLogcheckLevel = errors.New("invalid logcheck level, expected paranoid, server or workstation")
+)
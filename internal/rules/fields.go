@@ -0,0 +1,246 @@
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Field rules (#!fields) test the fields of log messages written in JSON,
+// one or more conditions joined by "&&":
+//
+//	level in [debug, info]
+//	status < 500 && path =~ ^/health
+//	user.name != admin
+//
+// Operators are == != < <= > >= (numbers), =~ !~ (regexps), in and not in
+// (lists). Nested fields use dots. Values may be quoted with double quotes.
+// A missing field only satisfies != !~ and not in.
+
+const conditionSeparator = "&&"
+
+// Field rule operators, longest first for parsing.
+var conditionOperators = []string{"not in", "==", "!=", "<=", ">=", "=~", "!~", "<", ">", "in"}
+
+// condition is a test of a field rule.
+type condition struct {
+	field  string
+	op     string
+	value  string
+	values []string // in, not in
+	num    float64
+	isNum  bool // value is a number
+	re     *regexp.Regexp
+	icase  bool
+}
+
+// parseConditions parses the conditions of a field rule.
+func parseConditions(expr string, icase bool) ([]condition, error) {
+	var conditions []condition
+	for _, part := range strings.Split(expr, conditionSeparator) {
+		c, err := parseCondition(strings.TrimSpace(part), icase)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, c)
+	}
+	return conditions, nil
+}
+
+func parseCondition(part string, icase bool) (condition, error) {
+	field, rest, found := strings.Cut(part, " ")
+	if !found || field == "" {
+		return condition{}, fmt.Errorf("%w: %q: expected <field> <operator> <value>", ErrInvalidFieldRule, part)
+	}
+	rest = strings.TrimSpace(rest)
+	c := condition{field: field, icase: icase}
+	for _, op := range conditionOperators {
+		if strings.HasPrefix(rest, op) {
+			c.op = op
+			c.value = strings.TrimSpace(rest[len(op):])
+			break
+		}
+	}
+	if c.op == "" || c.value == "" {
+		return condition{}, fmt.Errorf("%w: %q: expected <field> <operator> <value>", ErrInvalidFieldRule, part)
+	}
+
+	switch c.op {
+	case "in", "not in":
+		if !strings.HasPrefix(c.value, "[") || !strings.HasSuffix(c.value, "]") {
+			return condition{}, fmt.Errorf("%w: %q: expected a list [a, b]", ErrInvalidFieldRule, part)
+		}
+		for _, item := range strings.Split(c.value[1:len(c.value)-1], ",") {
+			c.values = append(c.values, unquote(strings.TrimSpace(item)))
+		}
+	case "=~", "!~":
+		pattern := unquote(c.value)
+		if icase {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return condition{}, fmt.Errorf("%w: %q: %w", ErrInvalidFieldRule, part, err)
+		}
+		c.re = re
+	case "<", "<=", ">", ">=":
+		num, err := strconv.ParseFloat(c.value, 64)
+		if err != nil {
+			return condition{}, fmt.Errorf("%w: %q: %s needs a number", ErrInvalidFieldRule, part, c.op)
+		}
+		c.num, c.isNum = num, true
+	default:
+		c.value = unquote(c.value)
+		if num, err := strconv.ParseFloat(c.value, 64); err == nil {
+			c.num, c.isNum = num, true
+		}
+	}
+	return c, nil
+}
+
+func unquote(value string) string {
+	if unquoted, err := strconv.Unquote(value); err == nil && strings.HasPrefix(value, `"`) {
+		return unquoted
+	}
+	return value
+}
+
+// match tests the condition against the fields of a message.
+func (c condition) match(fields map[string]any) bool {
+	raw, found := lookupField(fields, c.field)
+	value := fieldString(raw)
+	switch c.op {
+	case "==":
+		return found && c.equal(raw, value)
+	case "!=":
+		return !found || !c.equal(raw, value)
+	case "=~":
+		return found && c.re.MatchString(value)
+	case "!~":
+		return !found || !c.re.MatchString(value)
+	case "in", "not in":
+		in := false
+		for _, item := range c.values {
+			if c.equalString(value, item) {
+				in = true
+				break
+			}
+		}
+		if c.op == "in" {
+			return found && in
+		}
+		return !found || !in
+	}
+	num, ok := fieldNumber(raw)
+	if !found || !ok {
+		return false
+	}
+	switch c.op {
+	case "<":
+		return num < c.num
+	case "<=":
+		return num <= c.num
+	case ">":
+		return num > c.num
+	default:
+		return num >= c.num
+	}
+}
+
+func (c condition) equal(raw any, value string) bool {
+	if num, ok := fieldNumber(raw); ok && c.isNum {
+		return num == c.num
+	}
+	return c.equalString(value, c.value)
+}
+
+func (c condition) equalString(a, b string) bool {
+	if c.icase {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// lookupField returns the value of a dotted field path, an exact key having priority.
+func lookupField(fields map[string]any, path string) (any, bool) {
+	if value, ok := fields[path]; ok {
+		return value, true
+	}
+	head, tail, found := strings.Cut(path, ".")
+	if !found {
+		return nil, false
+	}
+	nested, ok := fields[head].(map[string]any)
+	if !ok {
+		return nil, false
+	}
+	return lookupField(nested, tail)
+}
+
+// fieldString formats a decoded JSON value for string comparisons.
+func fieldString(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	encoded, _ := json.Marshal(value)
+	return string(encoded)
+}
+
+// fieldNumber returns a decoded JSON value as a number, numeric strings included.
+func fieldNumber(value any) (float64, bool) {
+	switch v := value.(type) {
+	case json.Number:
+		num, err := v.Float64()
+		return num, err == nil
+	case float64:
+		return v, true
+	case string:
+		num, err := strconv.ParseFloat(v, 64)
+		return num, err == nil
+	}
+	return 0, false
+}
+
+// IsField reports whether the rule is a field rule, matched with MatchFields instead of Pattern.
+func (r Rule) IsField() bool {
+	return len(r.conditions) > 0
+}
+
+// MatchFields tests a field rule against the decoded fields of a JSON message.
+// nil fields (message not in JSON) never match.
+func (r Rule) MatchFields(fields map[string]any) bool {
+	if fields == nil {
+		return false
+	}
+	for _, c := range r.conditions {
+		if !c.match(fields) {
+			return false
+		}
+	}
+	return true
+}
+
+// DecodeFields decodes a JSON object message, numbers being kept as json.Number.
+// It returns nil if the message is not a JSON object.
+func DecodeFields(message string) map[string]any {
+	trimmed := strings.TrimSpace(message)
+	if !strings.HasPrefix(trimmed, "{") {
+		return nil
+	}
+	decoder := json.NewDecoder(strings.NewReader(trimmed))
+	decoder.UseNumber()
+	var fields map[string]any
+	if err := decoder.Decode(&fields); err != nil {
+		return nil
+	}
+	return fields
+}
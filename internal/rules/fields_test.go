@@ -0,0 +1,123 @@
+package rules_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sgaunet/awslogcheck/internal/rules"
+)
+
+// TestFieldRules tests the matching of field rules against JSON messages
+func TestFieldRules(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "json.rule")
+	writeFile(t, file, strings.Join([]string{
+		`^plain regexp`,
+		`#!fields`,
+		`level in [debug, info]`,
+		`status < 500 && path =~ ^/health`,
+		`http.method == "GET" && http.status == 200`,
+		`#!icase`,
+		`msg =~ ^connection reset`,
+		`#!regexp`,
+		`^back to regexp`,
+	}, "\n")+"\n")
+
+	loaded, err := rules.LoadFile(file)
+	if err != nil {
+		t.Fatalf("LoadFile returned error: %v", err)
+	}
+	if len(loaded) != 6 {
+		t.Fatalf("Expected 6 rules, got %d", len(loaded))
+	}
+	if loaded[0].IsField() || loaded[5].IsField() {
+		t.Error("Regexp rules should not be field rules")
+	}
+
+	tests := []struct {
+		name     string
+		rule     int
+		message  string
+		expected bool
+	}{
+		{"level in list", 1, `{"level":"info","msg":"started"}`, true},
+		{"level not in list", 1, `{"level":"error","msg":"failed"}`, false},
+		{"missing field", 1, `{"msg":"started"}`, false},
+		{"number below", 2, `{"status":200,"path":"/healthz"}`, true},
+		{"number above", 2, `{"status":503,"path":"/healthz"}`, false},
+		{"numeric string", 2, `{"status":"404","path":"/health"}`, true},
+		{"second condition fails", 2, `{"status":200,"path":"/api"}`, false},
+		{"nested fields", 3, `{"http":{"method":"GET","status":200}}`, true},
+		{"nested field differs", 3, `{"http":{"method":"POST","status":200}}`, false},
+		{"icase regexp", 4, `{"msg":"Connection reset by peer"}`, true},
+		{"not JSON", 1, `level=info msg=started`, false},
+		{"JSON array", 1, `[{"level":"info"}]`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := loaded[tt.rule]
+			if !rule.IsField() {
+				t.Fatalf("Rule %q should be a field rule", rule.Raw)
+			}
+			if result := rule.MatchFields(rules.DecodeFields(tt.message)); result != tt.expected {
+				t.Errorf("%q on %s: expected %v, got %v", rule.Raw, tt.message, tt.expected, result)
+			}
+		})
+	}
+}
+
+// TestFieldRulesNegativeOperators tests that missing fields satisfy the negative operators
+func TestFieldRulesNegativeOperators(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "json.rule")
+	writeFile(t, file, "#!fields\nlevel not in [error, fatal]\nuser != admin\nmsg !~ panic\n")
+	loaded, err := rules.LoadFile(file)
+	if err != nil {
+		t.Fatalf("LoadFile returned error: %v", err)
+	}
+	fields := rules.DecodeFields(`{"other":"value"}`)
+	for _, rule := range loaded {
+		if !rule.MatchFields(fields) {
+			t.Errorf("%q should match a message without the field", rule.Raw)
+		}
+	}
+	fields = rules.DecodeFields(`{"level":"error","user":"admin","msg":"panic: nil map"}`)
+	for _, rule := range loaded {
+		if rule.MatchFields(fields) {
+			t.Errorf("%q should not match", rule.Raw)
+		}
+	}
+}
+
+// TestFieldRulesErrors tests that invalid field rules are reported by the linter
+func TestFieldRulesErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "json.rule"), strings.Join([]string{
+		`#!fields`,
+		`level`,
+		`status < high`,
+		`level in debug`,
+		`msg =~ (broken`,
+		`level == info`,
+		`level == info`,
+	}, "\n")+"\n")
+
+	findings, err := rules.LintDir(dir)
+	if err != nil {
+		t.Fatalf("LintDir returned error: %v", err)
+	}
+	lines := make(map[int]string)
+	for _, finding := range findings {
+		lines[finding.Line] = finding.Message
+	}
+	for _, line := range []int{2, 3, 4, 5} {
+		if !strings.Contains(lines[line], "invalid field rule") {
+			t.Errorf("Line %d: expected an invalid field rule, got %q", line, lines[line])
+		}
+	}
+	if !strings.Contains(lines[7], "duplicate of") {
+		t.Errorf("Line 7: expected a duplicate, got %q", lines[7])
+	}
+	if _, ok := lines[6]; ok {
+		t.Errorf("Line 6: unexpected finding %q", lines[6])
+	}
+}
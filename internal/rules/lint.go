@@ -0,0 +1,256 @@
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+)
+
+// Severities of the lint findings.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// minLiteralLength is the length under which an unanchored literal rule is considered too broad.
+const minLiteralLength = 4
+
+// Lines used to detect the rules matching every line.
+var lintSampleLines = []string{"", "x", "2024-01-01 10:00:00 ERROR: The quick brown fox jumps over 13 lazy dogs !"}
+
+// A bracket expression containing a word, like [info], is a character class, not the word.
+var bracketWordRegexp = regexp.MustCompile(`(^|[^\\])(\[[A-Za-z]{3,}\])`)
+
+// RE2 limitations, checked when a rule does not compile.
+var re2Unsupported = []struct {
+	re      *regexp.Regexp
+	message string
+}{
+	{regexp.MustCompile(`\(\?[=!]`), "lookahead assertions are not supported by RE2"},
+	{regexp.MustCompile(`\(\?<[=!]`), "lookbehind assertions are not supported by RE2"},
+	{regexp.MustCompile(`\(\?>`), "atomic groups are not supported by RE2"},
+	{regexp.MustCompile(`\\[1-9]`), "backreferences are not supported by RE2"},
+	{regexp.MustCompile(`[*+?}]\+`), "possessive quantifiers are not supported by RE2"},
+}
+
+// Finding is an issue found in a rule.
+type Finding struct {
+	File     string
+	Line     int
+	Severity string
+	Message  string
+	Raw      string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s:%d: %s: %s: %s", f.File, f.Line, f.Severity, f.Message, f.Raw)
+}
+
+// literalRule describes a rule matching a fixed text.
+type literalRule struct {
+	text            string
+	foldCase        bool
+	anchored        bool // ^ or $ used
+	redundantDotAll bool // Unanchored rule starting or ending with .*
+}
+
+// Lint checks the rules and returns the findings, in the order of the rules.
+// Problems found while loading the rules are reported as errors.
+func Lint(loaded []Rule, problems []Problem) []Finding {
+	findings := make([]Finding, 0, len(problems))
+	for _, problem := range problems {
+		findings = append(findings, Finding{
+			File: problem.File, Line: problem.Line, Severity: SeverityError,
+			Message: problem.Err.Error(), Raw: problem.Raw,
+		})
+	}
+
+	literals := make(map[int]literalRule, len(loaded))
+	firstOccurrence := make(map[string]Rule, len(loaded))
+	for i, rule := range loaded {
+		newFinding := func(severity string, format string, args ...any) {
+			findings = append(findings, Finding{
+				File: rule.File, Line: rule.Line, Severity: severity,
+				Message: fmt.Sprintf(format, args...), Raw: rule.Raw,
+			})
+		}
+		key := fmt.Sprintf("%t:%s", rule.Alert, rule.Pattern)
+		var re *regexp.Regexp
+		if rule.IsField() {
+			key = fmt.Sprintf("%t:fields:%s", rule.Alert, strings.TrimSpace(rule.Raw))
+		} else {
+			var err error
+			if re, err = regexp.Compile(rule.Pattern); err != nil {
+				newFinding(SeverityError, "%s", re2Error(rule.Pattern, err))
+				continue
+			}
+		}
+		if first, ok := firstOccurrence[key]; ok {
+			newFinding(SeverityWarning, "duplicate of %s:%d", first.File, first.Line)
+			continue
+		}
+		firstOccurrence[key] = rule
+		if rule.IsField() {
+			continue // Conditions are checked when loading
+		}
+
+		if message := matchesEverything(rule.Pattern, re); message != "" {
+			newFinding(SeverityError, "%s", message)
+			continue
+		}
+		if m := bracketWordRegexp.FindStringSubmatch(rule.Pattern); m != nil {
+			newFinding(SeverityWarning, "%s is a character class matching a single character, escape it as %s to match the text",
+				m[2], regexp.QuoteMeta(m[2]))
+		}
+		if strings.Contains(rule.Pattern, "()") {
+			newFinding(SeverityWarning, "empty group (), escape the parenthesis to match them")
+		}
+		lit, ok := parseLiteralRule(rule.Pattern)
+		if !ok {
+			continue
+		}
+		literals[i] = lit
+		if lit.redundantDotAll {
+			newFinding(SeverityWarning, "leading or trailing .* is useless, the rule matches %q anywhere in the line: anchor it or make it more specific",
+				lit.text)
+		} else if !lit.anchored && len(lit.text) < minLiteralLength {
+			newFinding(SeverityWarning, "too broad, the rule matches every line containing %q", lit.text)
+		}
+	}
+	return append(findings, shadowedRules(loaded, literals)...)
+}
+
+// shadowedRules reports the literal rules already covered by a broader literal rule.
+func shadowedRules(loaded []Rule, literals map[int]literalRule) []Finding {
+	var findings []Finding
+	for i, rule := range loaded {
+		narrow, ok := literals[i]
+		if !ok {
+			continue
+		}
+		for j, broader := range loaded {
+			wide, ok := literals[j]
+			if i == j || !ok || wide.anchored || rule.Alert != broader.Alert || rule.Pattern == broader.Pattern {
+				continue
+			}
+			if narrow.foldCase && !wide.foldCase {
+				continue
+			}
+			text, sub := narrow.text, wide.text
+			if wide.foldCase {
+				text, sub = strings.ToLower(text), strings.ToLower(sub)
+			}
+			if strings.Contains(text, sub) {
+				findings = append(findings, Finding{
+					File: rule.File, Line: rule.Line, Severity: SeverityWarning,
+					Message: fmt.Sprintf("shadowed by the broader rule %s:%d (%s)", broader.File, broader.Line, broader.Raw),
+					Raw:     rule.Raw,
+				})
+				break
+			}
+		}
+	}
+	return findings
+}
+
+// matchesEverything returns a message if the rule matches any line.
+func matchesEverything(pattern string, re *regexp.Regexp) string {
+	parsed, err := syntax.Parse(pattern, syntax.Perl)
+	if err == nil {
+		parsed = parsed.Simplify()
+		for parsed.Op == syntax.OpCapture {
+			parsed = parsed.Sub[0]
+		}
+		if parsed.Op == syntax.OpEmptyMatch {
+			return "empty pattern, the rule matches every line"
+		}
+	}
+	for _, sample := range lintSampleLines {
+		if !re.MatchString(sample) {
+			return ""
+		}
+	}
+	return "the rule matches every line"
+}
+
+// parseLiteralRule checks if pattern only matches a fixed text, optionally
+// anchored or surrounded by .*.
+func parseLiteralRule(pattern string) (literalRule, bool) {
+	parsed, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return literalRule{}, false
+	}
+	parsed = parsed.Simplify()
+	parts := []*syntax.Regexp{parsed}
+	if parsed.Op == syntax.OpConcat {
+		parts = parsed.Sub
+	}
+
+	var (
+		res    literalRule
+		text   strings.Builder
+		dotAll bool
+	)
+	for i, part := range parts {
+		first, last := i == 0, i == len(parts)-1
+		switch {
+		case part.Op == syntax.OpLiteral:
+			if text.Len() > 0 && res.foldCase != (part.Flags&syntax.FoldCase != 0) {
+				return literalRule{}, false
+			}
+			res.foldCase = part.Flags&syntax.FoldCase != 0
+			text.WriteString(string(part.Rune))
+		case (first && (part.Op == syntax.OpBeginText || part.Op == syntax.OpBeginLine)) ||
+			(last && (part.Op == syntax.OpEndText || part.Op == syntax.OpEndLine)):
+			res.anchored = true
+		case (first || last) && part.Op == syntax.OpStar &&
+			(part.Sub[0].Op == syntax.OpAnyCharNotNL || part.Sub[0].Op == syntax.OpAnyChar):
+			dotAll = true
+		default:
+			return literalRule{}, false
+		}
+	}
+	if text.Len() == 0 {
+		return literalRule{}, false
+	}
+	res.text = text.String()
+	res.redundantDotAll = dotAll && !res.anchored
+	return res, true
+}
+
+// re2Error explains why a rule does not compile.
+func re2Error(pattern string, err error) string {
+	for _, unsupported := range re2Unsupported {
+		if unsupported.re.MatchString(pattern) {
+			return unsupported.message
+		}
+	}
+	return "invalid regexp: " + err.Error()
+}
+
+// LintDir loads and checks a rules directory.
+func LintDir(dir string) ([]Finding, error) {
+	l := newLoader(flags{})
+	loaded, err := l.loadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	return Lint(loaded, l.problems), nil
+}
+
+// LintLogcheckDir loads and checks a logcheck rules directory, every category
+// is checked on its own.
+func LintLogcheckDir(dir string, level string) ([]Finding, error) {
+	loaded, err := LoadLogcheckDir(dir, level)
+	if err != nil {
+		return nil, err
+	}
+	findings := Lint(loaded.Ignore, loaded.Problems)
+	for _, category := range [][]Rule{
+		loaded.Violations, loaded.ViolationsIgnore, loaded.Cracking, loaded.CrackingIgnore,
+	} {
+		findings = append(findings, Lint(category, nil)...)
+	}
+	return findings, nil
+}
@@ -0,0 +1,78 @@
+package rules_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sgaunet/awslogcheck/internal/rules"
+)
+
+// TestLint tests the findings of the rule linter
+func TestLint(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.rule"), strings.Join([]string{
+		`^DEBUG:`,                   // 1: fine
+		`(foo`,                      // 2: invalid
+		`^(?!health)`,               // 3: RE2 incompatible
+		`.*`,                        // 4: matches everything
+		`()`,                        // 5: empty
+		`[info]`,                    // 6: character class
+		`.*warning`,                 // 7: redundant .*
+		`ok`,                        // 8: too broad
+		`Successfully Reconciled`,   // 9: fine
+		`^DEBUG:`,                   // 10: duplicate
+		`Successfully Reconciled x`, // 11: shadowed by line 9
+	}, "\n")+"\n")
+	writeFile(t, filepath.Join(dir, "b.rule"), "#!alert\nSuccessfully Reconciled x\n")
+
+	findings, err := rules.LintDir(dir)
+	if err != nil {
+		t.Fatalf("LintDir returned error: %v", err)
+	}
+
+	expected := map[int]string{
+		2:  "invalid regexp",
+		3:  "lookahead assertions are not supported by RE2",
+		4:  "matches every line",
+		5:  "empty pattern",
+		6:  "character class",
+		7:  "leading or trailing .* is useless",
+		8:  "too broad",
+		10: "duplicate of",
+		11: "shadowed by the broader rule",
+	}
+	got := make(map[int]string)
+	for _, finding := range findings {
+		if filepath.Base(finding.File) != "a.rule" {
+			t.Errorf("Unexpected finding in alert rules: %s", finding)
+			continue
+		}
+		got[finding.Line] += finding.Message
+	}
+	for line, message := range expected {
+		if !strings.Contains(got[line], message) {
+			t.Errorf("Line %d: expected %q, got %q", line, message, got[line])
+		}
+	}
+	for line := range got {
+		if _, ok := expected[line]; !ok {
+			t.Errorf("Unexpected finding on line %d: %s", line, got[line])
+		}
+	}
+}
+
+// TestLintLogcheckDir tests that untranslatable logcheck rules are reported
+func TestLintLogcheckDir(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "ignore.d.server", "a"), "(x)\\1\nsshd.*Accepted\n")
+	writeFile(t, filepath.Join(dir, "violations.d", "a"), "sshd.*Accepted\n")
+
+	findings, err := rules.LintLogcheckDir(dir, rules.LevelServer)
+	if err != nil {
+		t.Fatalf("LintLogcheckDir returned error: %v", err)
+	}
+	if len(findings) != 1 || findings[0].Severity != rules.SeverityError || findings[0].Line != 1 {
+		t.Errorf("Expected only the backreference error, got %v", findings)
+	}
+}
@@ -0,0 +1,82 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Logcheck levels, from the most to the least verbose.
+const (
+	LevelParanoid    = "paranoid"
+	LevelServer      = "server"
+	LevelWorkstation = "workstation"
+)
+
+// ignoreDirsByLevel lists the ignore.d directories used by each logcheck level.
+var ignoreDirsByLevel = map[string][]string{
+	LevelParanoid:    {"ignore.d.paranoid"},
+	LevelServer:      {"ignore.d.paranoid", "ignore.d.server"},
+	LevelWorkstation: {"ignore.d.paranoid", "ignore.d.server", "ignore.d.workstation"},
+}
+
+// Logcheck holds the rules of a Debian logcheck style directory.
+type Logcheck struct {
+	Ignore           []Rule    // ignore.d.<level>: lines that are not reported
+	Violations       []Rule    // violations.d: security events
+	ViolationsIgnore []Rule    // violations.ignore.d: false positives of violations.d
+	Cracking         []Rule    // cracking.d: security alerts
+	CrackingIgnore   []Rule    // cracking.ignore.d: false positives of cracking.d
+	Problems         []Problem // rules that could not be translated to RE2
+}
+
+// LoadLogcheckDir loads a logcheck rules directory (like /etc/logcheck) for
+// the given level. Rules use the POSIX extended syntax of egrep and are
+// translated to RE2, untranslatable rules are skipped and listed in Problems.
+// Missing category directories are considered empty.
+func LoadLogcheckDir(dir string, level string) (*Logcheck, error) {
+	if level == "" {
+		level = LevelServer
+	}
+	ignoreDirs, ok := ignoreDirsByLevel[level]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidLogcheckLevel, level)
+	}
+
+	l := newLoader(flags{ere: true})
+	res := &Logcheck{}
+	for _, ignoreDir := range ignoreDirs {
+		loaded, err := l.loadCategory(dir, ignoreDir)
+		if err != nil {
+			return nil, err
+		}
+		res.Ignore = append(res.Ignore, loaded...)
+	}
+	categories := []struct {
+		name  string
+		rules *[]Rule
+	}{
+		{"violations.d", &res.Violations},
+		{"violations.ignore.d", &res.ViolationsIgnore},
+		{"cracking.d", &res.Cracking},
+		{"cracking.ignore.d", &res.CrackingIgnore},
+	}
+	for _, category := range categories {
+		loaded, err := l.loadCategory(dir, category.name)
+		if err != nil {
+			return nil, err
+		}
+		*category.rules = loaded
+	}
+	res.Problems = l.problems
+	return res, nil
+}
+
+func (l *loader) loadCategory(dir string, category string) ([]Rule, error) {
+	categoryDir := filepath.Join(dir, category)
+	if _, err := os.Stat(categoryDir); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return l.loadDir(categoryDir)
+}
@@ -0,0 +1,307 @@
+// Package rules parses the rule files used by awslogcheck.
+//
+// A rule file contains one regular expression per line. Blank lines and lines
+// starting with '#' are ignored. Lines starting with "#!" are directives:
+//
+//	#!icase          following rules are case-insensitive
+//	#!literal        following rules are matched as plain strings, not regexps
+//	#!regexp         following rules are regexps again (default)
+//	#!ere            following rules are POSIX extended regexps (egrep, logcheck)
+//	#!fields         following rules test the fields of JSON messages (see fields.go)
+//	#!alert          following rules are alert rules: matching lines are always reported
+//	#!ignore         following rules are ignore rules again (default)
+//	#!include <path> include another rule file (relative to the current file)
+//
+// Flags are scoped to the file that sets them, they are not inherited by
+// included files.
+package rules
+
+import (
+	"bufio"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	commentPrefix   = "#"
+	directivePrefix = "#!"
+)
+
+// Rule is a pattern read from a rule file.
+type Rule struct {
+	Pattern string // Regular expression with the file flags applied
+	Raw     string // Pattern as written in the file
+	File    string
+	Line    int
+	Alert   bool // Matching lines must be reported even if an ignore rule matches
+
+	conditions []condition // Field rule, Pattern is empty
+}
+
+// Problem is a rule that could not be loaded.
+type Problem struct {
+	Raw  string
+	File string
+	Line int
+	Err  error
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s:%d: %s: %v", p.File, p.Line, p.Raw, p.Err)
+}
+
+// flags holds the directives currently active while parsing a file.
+type flags struct {
+	icase   bool
+	literal bool
+	ere     bool
+	fields  bool
+	alert   bool
+}
+
+func (f flags) apply(raw string) (string, error) {
+	pattern := raw
+	switch {
+	case f.literal:
+		pattern = regexp.QuoteMeta(pattern)
+	case f.ere:
+		translated, err := TranslateERE(pattern)
+		if err != nil {
+			return "", err
+		}
+		pattern = translated
+	}
+	if f.icase {
+		pattern = "(?i)" + pattern
+	}
+	return pattern, nil
+}
+
+// loader parses rule files, starting every file with the same base flags.
+type loader struct {
+	base     flags
+	stack    map[string]bool
+	loaded   map[string]bool // Files of the current directory already loaded, included ones too
+	problems []Problem
+}
+
+func newLoader(base flags) *loader {
+	return &loader{base: base, stack: map[string]bool{}, loaded: map[string]bool{}}
+}
+
+// IsIgnoredFile reports whether a file or directory name must be skipped when
+// walking a rules directory: hidden entries (including the "..data" links
+// created by Kubernetes ConfigMap volumes) and editor or package manager
+// backups.
+func IsIgnoredFile(name string) bool {
+	if strings.HasPrefix(name, ".") || strings.HasSuffix(name, "~") {
+		return true
+	}
+	switch filepath.Ext(name) {
+	case ".swp", ".swo", ".bak", ".orig", ".rej", ".dpkg-old", ".dpkg-new", ".dpkg-dist":
+		return true
+	}
+	return false
+}
+
+// LoadDir walks dir and returns the rules of every rule file found. Incorrect field
+// rules, and untranslatable #!ere rules, are skipped and returned as problems.
+func LoadDir(dir string) ([]Rule, []Problem, error) {
+	l := newLoader(flags{})
+	loaded, err := l.loadDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	return loaded, l.problems, nil
+}
+
+// Files returns the absolute paths of the rule files loaded from dir: the files of the walk,
+// and the ones they include, possibly outside of dir.
+func Files(dir string) ([]string, error) {
+	l := newLoader(flags{})
+	if _, err := l.loadDir(dir); err != nil {
+		return nil, err
+	}
+	return slices.Sorted(maps.Keys(l.loaded)), nil
+}
+
+func (l *loader) loadDir(dir string) ([]Rule, error) {
+	// A file included by another one, and found by the walk, is loaded once
+	l.loaded = map[string]bool{}
+	var rules []Rule
+	err := filepath.Walk(dir, func(pathitem string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if pathitem != dir && IsIgnoredFile(info.Name()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() {
+			return nil
+		}
+		fileRules, err := l.loadFile(pathitem)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, fileRules...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk rules directory: %w", err)
+	}
+	return rules, nil
+}
+
+// LoadFile parses a single rule file, following its include directives.
+func LoadFile(filename string) ([]Rule, error) {
+	l := newLoader(flags{})
+	return l.loadFile(filename)
+}
+
+func (l *loader) loadFile(filename string) ([]Rule, error) {
+	absPath, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+	if l.stack[absPath] {
+		return nil, fmt.Errorf("%w: %s", ErrIncludeCycle, filename)
+	}
+	if l.loaded[absPath] {
+		return []Rule{}, nil
+	}
+	l.loaded[absPath] = true
+	l.stack[absPath] = true
+	defer delete(l.stack, absPath)
+
+	// #nosec G304 - filename comes from the trusted rules directory
+	ruleFile, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open rule file: %w", err)
+	}
+	defer func() { _ = ruleFile.Close() }()
+
+	var (
+		rules   []Rule
+		current = l.base
+		lineNum int
+	)
+	scanner := bufio.NewScanner(ruleFile)
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			continue
+		case strings.HasPrefix(trimmed, directivePrefix):
+			included, err := l.directive(&current, trimmed, filename, lineNum)
+			if err != nil {
+				return nil, err
+			}
+			rules = append(rules, included...)
+		case strings.HasPrefix(trimmed, commentPrefix):
+			continue
+		case current.fields:
+			conditions, err := parseConditions(trimmed, current.icase)
+			if err != nil {
+				l.problems = append(l.problems, Problem{Raw: line, File: filename, Line: lineNum, Err: err})
+				continue
+			}
+			rules = append(rules, Rule{
+				Raw:        line,
+				File:       filename,
+				Line:       lineNum,
+				Alert:      current.alert,
+				conditions: conditions,
+			})
+		default:
+			pattern, err := current.apply(line)
+			if err != nil {
+				l.problems = append(l.problems, Problem{Raw: line, File: filename, Line: lineNum, Err: err})
+				continue
+			}
+			rules = append(rules, Rule{
+				Pattern: pattern,
+				Raw:     line,
+				File:    filename,
+				Line:    lineNum,
+				Alert:   current.alert,
+			})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rule file %s: %w", filename, err)
+	}
+	return rules, nil
+}
+
+// directive applies a "#!" line to the current flags, and returns the rules
+// of the included files for include directives.
+func (l *loader) directive(f *flags, line string, filename string, lineNum int) ([]Rule, error) {
+	name, arg, _ := strings.Cut(strings.TrimPrefix(line, directivePrefix), " ")
+	arg = strings.TrimSpace(arg)
+	switch name {
+	case "icase":
+		f.icase = true
+	case "literal":
+		f.literal = true
+		f.fields = false
+	case "regexp":
+		f.literal = false
+		f.ere = false
+		f.fields = false
+	case "ere":
+		f.literal = false
+		f.ere = true
+		f.fields = false
+	case "fields":
+		f.fields = true
+	case "alert":
+		f.alert = true
+	case "ignore":
+		f.alert = false
+	case "include":
+		if arg == "" {
+			return nil, fmt.Errorf("%w: %s:%d: include needs a path", ErrInvalidDirective, filename, lineNum)
+		}
+		return l.includeFiles(filename, arg)
+	default:
+		return nil, fmt.Errorf("%w: %s:%d: %s", ErrInvalidDirective, filename, lineNum, line)
+	}
+	return nil, nil
+}
+
+// includeFiles loads the files matched by pattern, relative to the directory of
+// the including file.
+func (l *loader) includeFiles(filename string, pattern string) ([]Rule, error) {
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(filepath.Dir(filename), pattern)
+	}
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid include pattern %s: %w", pattern, err)
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrIncludeNotFound, pattern)
+	}
+	var rules []Rule
+	for _, match := range matches {
+		if IsIgnoredFile(filepath.Base(match)) {
+			continue
+		}
+		included, err := l.loadFile(match)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, included...)
+	}
+	return rules, nil
+}
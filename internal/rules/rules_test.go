@@ -0,0 +1,235 @@
+package rules_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/sgaunet/awslogcheck/internal/rules"
+)
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+}
+
+func patterns(loaded []rules.Rule) []string {
+	res := make([]string, 0, len(loaded))
+	for _, r := range loaded {
+		res = append(res, r.Pattern)
+	}
+	return res
+}
+
+// TestLoadFileGrammar tests comments, blank lines and flags
+func TestLoadFileGrammar(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "app.rule")
+	writeFile(t, file, "# a comment\n\n   \n^DEBUG:\n  # indented comment\r\n#!icase\nwarning\n#!literal\n[info]\n#!regexp\nlevel=.*\n")
+
+	loaded, err := rules.LoadFile(file)
+	if err != nil {
+		t.Fatalf("LoadFile returned error: %v", err)
+	}
+	expected := []string{"^DEBUG:", "(?i)warning", `(?i)\[info\]`, "(?i)level=.*"}
+	got := patterns(loaded)
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %d rules, got %d: %v", len(expected), len(got), got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("Rule %d: expected %q, got %q", i, expected[i], got[i])
+		}
+	}
+	if loaded[0].Line != 4 || loaded[0].File != file || loaded[0].Alert {
+		t.Errorf("Unexpected rule position %s:%d", loaded[0].File, loaded[0].Line)
+	}
+
+	re := regexp.MustCompile(loaded[2].Pattern)
+	if !re.MatchString("[INFO] started") || re.MatchString("i") {
+		t.Error("Literal rule should match the bracketed string only")
+	}
+}
+
+// TestLoadFileAlertDirective tests the alert and ignore directives
+func TestLoadFileAlertDirective(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "alerts.rule")
+	writeFile(t, file, "#!alert\nOOMKilled\n^panic:\n#!ignore\nlevel=info\n")
+
+	loaded, err := rules.LoadFile(file)
+	if err != nil {
+		t.Fatalf("LoadFile returned error: %v", err)
+	}
+	expected := []bool{true, true, false}
+	if len(loaded) != len(expected) {
+		t.Fatalf("Expected %d rules, got %d", len(expected), len(loaded))
+	}
+	for i := range expected {
+		if loaded[i].Alert != expected[i] {
+			t.Errorf("Rule %q: expected alert=%v", loaded[i].Raw, expected[i])
+		}
+	}
+}
+
+// TestLoadFileInclude tests include directives and cycle detection
+func TestLoadFileInclude(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "main.rule"), "#!icase\nmain\n#!include common/*.rule\n")
+	writeFile(t, filepath.Join(dir, "common", "a.rule"), "common-a\n")
+	writeFile(t, filepath.Join(dir, "common", "b.rule"), "common-b\n")
+
+	loaded, err := rules.LoadFile(filepath.Join(dir, "main.rule"))
+	if err != nil {
+		t.Fatalf("LoadFile returned error: %v", err)
+	}
+	got := patterns(loaded)
+	expected := []string{"(?i)main", "common-a", "common-b"}
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("Rule %d: expected %q, got %q", i, expected[i], got[i])
+		}
+	}
+
+	writeFile(t, filepath.Join(dir, "loop.rule"), "#!include loop.rule\n")
+	if _, err := rules.LoadFile(filepath.Join(dir, "loop.rule")); !errors.Is(err, rules.ErrIncludeCycle) {
+		t.Errorf("Expected include cycle error, got %v", err)
+	}
+
+	writeFile(t, filepath.Join(dir, "missing.rule"), "#!include nothing.rule\n")
+	if _, err := rules.LoadFile(filepath.Join(dir, "missing.rule")); !errors.Is(err, rules.ErrIncludeNotFound) {
+		t.Errorf("Expected include not found error, got %v", err)
+	}
+
+	writeFile(t, filepath.Join(dir, "bad.rule"), "#!unknown\n")
+	if _, err := rules.LoadFile(filepath.Join(dir, "bad.rule")); !errors.Is(err, rules.ErrInvalidDirective) {
+		t.Errorf("Expected invalid directive error, got %v", err)
+	}
+}
+
+// TestLoadDirSkipsHiddenAndBackupFiles tests the files ignored during the walk
+func TestLoadDirSkipsHiddenAndBackupFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "app.rule"), "kept\n")
+	writeFile(t, filepath.Join(dir, ".app.rule.swp"), "swap\n")
+	writeFile(t, filepath.Join(dir, "app.rule~"), "backup\n")
+	writeFile(t, filepath.Join(dir, "app.rule.bak"), "backup\n")
+	writeFile(t, filepath.Join(dir, "..2024_01_01", "app.rule"), "configmap copy\n")
+
+	loaded, _, err := rules.LoadDir(dir)
+	if err != nil {
+		t.Fatalf("LoadDir returned error: %v", err)
+	}
+	got := patterns(loaded)
+	if len(got) != 1 || got[0] != "kept" {
+		t.Errorf("Expected only the kept rule, got %v", got)
+	}
+}
+
+// TestLoadDirIncludedFilesOnce tests that a file included and found by the walk is loaded once
+func TestLoadDirIncludedFilesOnce(t *testing.T) {
+	for _, including := range []string{"a.rule", "z.rule"} {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, including), "#!include common/*.rule\nown\n")
+		writeFile(t, filepath.Join(dir, "common", "base.rule"), "shared\n")
+
+		loaded, _, err := rules.LoadDir(dir)
+		if err != nil {
+			t.Fatalf("LoadDir returned error: %v", err)
+		}
+		if got := patterns(loaded); len(got) != 2 {
+			t.Errorf("%s: expected the shared rule once, got %v", including, got)
+		}
+	}
+}
+
+// TestTranslateERE tests the POSIX ERE to RE2 translation
+func TestTranslateERE(t *testing.T) {
+	tests := []struct {
+		name      string
+		pattern   string
+		expected  string
+		expectErr error
+	}{
+		{name: "Plain pattern", pattern: `^sshd\[[0-9]+\]: Accepted`, expected: `^sshd\[[0-9]+\]: Accepted`},
+		{name: "POSIX classes", pattern: `[[:alnum:]_-]+ [[:digit:]]{2}`, expected: `[[:alnum:]_-]+ [[:digit:]]{2}`},
+		{name: "GNU word boundaries", pattern: `\<kernel\>`, expected: `\bkernel\b`},
+		{name: "BSD word boundaries", pattern: `[[:<:]]kernel[[:>:]]`, expected: `\bkernel\b`},
+		{name: "Literal closing bracket", pattern: `[]a]`, expected: `[\]a]`},
+		{name: "Backslash in bracket", pattern: `[\.]`, expected: `[\\.]`},
+		{name: "Empty lower bound", pattern: `a{,3}`, expected: `a{0,3}`},
+		{name: "Backreference", pattern: `(a)\1`, expectErr: rules.ErrUntranslatable},
+		{name: "Collating element", pattern: `[[.hyphen.]]`, expectErr: rules.ErrUntranslatable},
+		{name: "Repeat count too large", pattern: `a{2000}`, expectErr: rules.ErrUntranslatable},
+		{name: "Unterminated bracket", pattern: `[abc`, expectErr: rules.ErrInvalidERE},
+		{name: "Unbalanced parenthesis", pattern: `(abc`, expectErr: rules.ErrInvalidERE},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rules.TranslateERE(tt.pattern)
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Errorf("Expected %v, got %q, %v", tt.expectErr, got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestLoadLogcheckDir tests the logcheck layout and levels
+func TestLoadLogcheckDir(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "ignore.d.paranoid", "cron"), "CRON\\[[0-9]+\\]\n")
+	writeFile(t, filepath.Join(dir, "ignore.d.server", "sshd"), "sshd.*Accepted\n(x)\\1\n")
+	writeFile(t, filepath.Join(dir, "ignore.d.workstation", "gnome"), "gnome\n")
+	writeFile(t, filepath.Join(dir, "violations.d", "su"), "FAILED su\n")
+	writeFile(t, filepath.Join(dir, "violations.ignore.d", "su"), "FAILED su for nobody\n")
+	writeFile(t, filepath.Join(dir, "cracking.d", "attack"), "attackalert\n")
+
+	loaded, err := rules.LoadLogcheckDir(dir, "")
+	if err != nil {
+		t.Fatalf("LoadLogcheckDir returned error: %v", err)
+	}
+	if len(loaded.Ignore) != 2 {
+		t.Errorf("Expected 2 ignore rules for server level, got %d", len(loaded.Ignore))
+	}
+	if len(loaded.Violations) != 1 || len(loaded.ViolationsIgnore) != 1 || len(loaded.Cracking) != 1 {
+		t.Errorf("Unexpected categories: %+v", loaded)
+	}
+	if len(loaded.CrackingIgnore) != 0 {
+		t.Errorf("Missing cracking.ignore.d should give no rules")
+	}
+	if len(loaded.Problems) != 1 || loaded.Problems[0].Line != 2 {
+		t.Errorf("Expected the backreference to be reported, got %v", loaded.Problems)
+	}
+
+	loaded, err = rules.LoadLogcheckDir(dir, rules.LevelWorkstation)
+	if err != nil {
+		t.Fatalf("LoadLogcheckDir returned error: %v", err)
+	}
+	if len(loaded.Ignore) != 3 {
+		t.Errorf("Expected 3 ignore rules for workstation level, got %d", len(loaded.Ignore))
+	}
+
+	if _, err := rules.LoadLogcheckDir(dir, "laptop"); !errors.Is(err, rules.ErrInvalidLogcheckLevel) {
+		t.Errorf("Expected invalid level error, got %v", err)
+	}
+}
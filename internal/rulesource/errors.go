@@ -0,0 +1,15 @@
+package rulesource
+
+import "errors"
+
+// Static errors for wrapping.
+var (
+	ErrNoCacheDir        = errors.New("no cache directory for the rule sources")
+	ErrUnsupportedSource = errors.New("unsupported rule source")
+	ErrUnsafePath        = errors.New("path outside of the rules directory")
+	ErrUnexpectedStatus  = errors.New("unexpected HTTP status")
+	ErrArchiveTooLarge   = errors.New("archive too large")
+	ErrNoS3Client        = errors.New("no S3 client")
+	ErrGitCommand        = errors.New("git command failed")
+	ErrInvalidGitSource  = errors.New("invalid git source")
+)
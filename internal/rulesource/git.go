@@ -0,0 +1,146 @@
+package rulesource
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// gitSource is a git repository, cloned with the git command.
+type gitSource struct {
+	cache   cacheEntry
+	repoURL string // URL given to git
+	ref     string // Branch or tag, the default branch if empty
+	path    string // Directory of the rules in the repository
+}
+
+// newGitSource parses git+<url>?ref=<ref>&path=<dir>.
+func newGitSource(rawURL string, opts Options) (*gitSource, error) {
+	repoURL := strings.TrimPrefix(rawURL, gitPrefix)
+	parsed, err := url.Parse(repoURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse rule source %s: %w", rawURL, err)
+	}
+	if parsed.Scheme == "" {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidGitSource, rawURL)
+	}
+	query := parsed.Query()
+	source := &gitSource{
+		cache: newCacheEntry(opts.CacheDir, "git", rawURL),
+		ref:   query.Get("ref"),
+		path:  query.Get("path"),
+	}
+	if strings.HasPrefix(source.ref, "-") {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidGitSource, rawURL)
+	}
+	if source.path != "" {
+		if _, err := localPath(source.cache.dir, source.path); err != nil {
+			return nil, err
+		}
+	}
+	query.Del("ref")
+	query.Del("path")
+	parsed.RawQuery = query.Encode()
+	source.repoURL = parsed.String()
+	return source, nil
+}
+
+// URL returns the URL of the source.
+func (s *gitSource) URL() string { return s.cache.rawURL }
+
+// Dir returns the directory of the rules in the working copy.
+func (s *gitSource) Dir() string {
+	if s.path == "" {
+		return s.cache.localDir()
+	}
+	dir, _ := localPath(s.cache.localDir(), s.path)
+	return dir
+}
+
+// Commit replaces the local copy with the staging one, if any.
+func (s *gitSource) Commit() error { return s.cache.commit() }
+
+// Discard removes the staging copy, if any.
+func (s *gitSource) Discard() error { return s.cache.discard() }
+
+// Sync clones the repository, or fetches the last commit of ref, in the staging copy.
+func (s *gitSource) Sync(ctx context.Context) (bool, error) {
+	if err := s.cache.discard(); err != nil {
+		return false, err
+	}
+	st := s.cache.load()
+	tmpDir, err := s.cache.tempDir()
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	if _, err := os.Stat(filepath.Join(s.cache.dir, ".git")); err != nil {
+		if err := s.clone(ctx, tmpDir); err != nil {
+			return false, err
+		}
+	} else {
+		// The working copy of the local copy is left as is, until the commit
+		remoteRef := s.ref
+		if remoteRef == "" {
+			remoteRef = "HEAD"
+		}
+		if _, err := runGit(ctx, s.cache.dir, "fetch", "--quiet", "--depth", "1", "origin", remoteRef); err != nil {
+			return false, err
+		}
+		fetched, err := runGit(ctx, s.cache.dir, "rev-parse", "FETCH_HEAD")
+		if err != nil {
+			return false, err
+		}
+		if fetched == st.Commit {
+			return false, nil
+		}
+		if err := copyDir(s.cache.dir, tmpDir); err != nil {
+			return false, err
+		}
+		if _, err := runGit(ctx, tmpDir, "reset", "--quiet", "--hard", "FETCH_HEAD"); err != nil {
+			return false, err
+		}
+	}
+	commit, err := runGit(ctx, tmpDir, "rev-parse", "HEAD")
+	if err != nil {
+		return false, err
+	}
+	if commit == st.Commit {
+		return false, nil
+	}
+	st.Commit = commit
+	return true, s.cache.stage(tmpDir, st)
+}
+
+// clone clones the repository in dir.
+func (s *gitSource) clone(ctx context.Context, dir string) error {
+	args := []string{"clone", "--quiet", "--depth", "1"}
+	if s.ref != "" {
+		args = append(args, "--branch", s.ref)
+	}
+	args = append(args, "--", s.repoURL, dir)
+	_, err := runGit(ctx, "", args...)
+	return err
+}
+
+// runGit runs git in dir, and returns its trimmed output.
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	// #nosec G204 - arguments are not interpreted by a shell
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%w: git %s: %w: %s", ErrGitCommand, args[0], err,
+			strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
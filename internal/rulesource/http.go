@@ -0,0 +1,166 @@
+package rulesource
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// maxArchiveSize is the maximum size of the extracted files of a tarball.
+const maxArchiveSize = 64 << 20
+
+// httpSource is a tarball downloaded with HTTP(S).
+type httpSource struct {
+	cache  cacheEntry
+	client *http.Client
+}
+
+func newHTTPSource(rawURL string, opts Options) *httpSource {
+	client := opts.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &httpSource{cache: newCacheEntry(opts.CacheDir, "https", rawURL), client: client}
+}
+
+// URL returns the URL of the tarball.
+func (s *httpSource) URL() string { return s.cache.rawURL }
+
+// Dir returns the directory where the tarball is extracted.
+func (s *httpSource) Dir() string { return s.cache.localDir() }
+
+// Commit replaces the local copy with the staging one, if any.
+func (s *httpSource) Commit() error { return s.cache.commit() }
+
+// Discard removes the staging copy, if any.
+func (s *httpSource) Discard() error { return s.cache.discard() }
+
+// Sync downloads the tarball if its ETag changed, and extracts it in the staging copy.
+func (s *httpSource) Sync(ctx context.Context) (bool, error) {
+	if err := s.cache.discard(); err != nil {
+		return false, err
+	}
+	st := s.cache.load()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.cache.rawURL, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	if st.ETag != "" {
+		req.Header.Set("If-None-Match", st.ETag)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to download %s: %w", s.cache.rawURL, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode == http.StatusNotModified {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("%w: %s: %s", ErrUnexpectedStatus, s.cache.rawURL, resp.Status)
+	}
+
+	tmpDir, err := s.cache.tempDir()
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	hash := sha256.New()
+	if err := extractTarball(io.TeeReader(resp.Body, hash), tmpDir); err != nil {
+		return false, fmt.Errorf("failed to extract %s: %w", s.cache.rawURL, err)
+	}
+	digest := hex.EncodeToString(hash.Sum(nil))
+	changed := digest != st.Digest
+	st.ETag = resp.Header.Get("ETag")
+	st.Digest = digest
+	if !changed {
+		return false, s.cache.save(st)
+	}
+	return true, s.cache.stage(tmpDir, st)
+}
+
+// extractTarball extracts the regular files and directories of a tar archive,
+// gzipped or not, into dir. Links and other special files are skipped.
+func extractTarball(r io.Reader, dir string) error {
+	buffered := bufio.NewReader(r)
+	var archive io.Reader = buffered
+	magic, err := buffered.Peek(2)
+	if err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return fmt.Errorf("failed to read gzip: %w", err)
+		}
+		defer func() { _ = gz.Close() }()
+		archive = gz
+	}
+
+	var total int64
+	tr := tar.NewReader(archive)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar: %w", err)
+		}
+		if header.Typeflag != tar.TypeDir && header.Typeflag != tar.TypeReg {
+			continue
+		}
+		target, err := localPath(dir, header.Name)
+		if err != nil {
+			if header.Typeflag == tar.TypeDir {
+				continue // "./"
+			}
+			return err
+		}
+		if header.Typeflag == tar.TypeDir {
+			if err := os.MkdirAll(target, dirPerm); err != nil {
+				return fmt.Errorf("failed to create %s: %w", target, err)
+			}
+			continue
+		}
+		total += header.Size
+		if total > maxArchiveSize {
+			return fmt.Errorf("%w: more than %d bytes", ErrArchiveTooLarge, maxArchiveSize)
+		}
+		if err := extractFile(tr, target, header.Size); err != nil {
+			return err
+		}
+	}
+	// Drain the body so that the digest covers the whole download
+	_, err = io.Copy(io.Discard, buffered)
+	if err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+	return nil
+}
+
+func extractFile(r io.Reader, target string, size int64) error {
+	if err := os.MkdirAll(filepath.Dir(target), dirPerm); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	// #nosec G304 - target is checked by localPath
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, filePerm)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", target, err)
+	}
+	if _, err := io.CopyN(f, r, size); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write %s: %w", target, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", target, err)
+	}
+	return nil
+}
@@ -0,0 +1,304 @@
+// Package rulesource fetches rule files from remote sources into a local cache.
+//
+// Supported URLs:
+//
+//	s3://bucket/prefix                          objects under the prefix
+//	https://host/rules.tar.gz                   tarball, gzipped or not
+//	git+https://host/repo.git?ref=main&path=dir  git repository (any git transport)
+//
+// Every source has its own directory in the cache. Its state (ETags, commit)
+// is kept next to it, so that a source is only downloaded again when it changed.
+// A sync writes a staging copy, which replaces the local one when it is committed:
+// rules rejected by the application are discarded, and downloaded again at the next sync.
+package rulesource
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	schemeS3    = "s3"
+	schemeHTTPS = "https"
+	schemeHTTP  = "http"
+	gitPrefix   = "git+"
+
+	dirPerm  = 0o750
+	filePerm = 0o600
+)
+
+// Source is a remote source of rules.
+type Source interface {
+	// URL returns the URL of the source.
+	URL() string
+	// Dir returns the local directory containing the rules of the source, the staging
+	// copy of the last Sync until it is committed or discarded.
+	Dir() string
+	// Sync writes the changes of the source in a staging copy, and reports whether there are some.
+	Sync(ctx context.Context) (bool, error)
+	// Commit replaces the local copy with the staging one, if any.
+	Commit() error
+	// Discard removes the staging copy, if any.
+	Discard() error
+}
+
+// Options contains the settings shared by the sources.
+type Options struct {
+	CacheDir   string       // Root of the local copies
+	S3         S3API        // Client for s3:// sources
+	HTTPClient *http.Client // Client for https:// sources, http.DefaultClient if nil
+}
+
+// state is the tracking information saved after a sync.
+type state struct {
+	URL     string            `json:"url"`
+	ETag    string            `json:"etag,omitempty"`    // https
+	Digest  string            `json:"digest,omitempty"`  // https, for servers without ETag
+	Objects map[string]string `json:"objects,omitempty"` // s3, ETag by key
+	Commit  string            `json:"commit,omitempty"`  // git
+}
+
+// cacheEntry is the location of a source in the cache.
+type cacheEntry struct {
+	rawURL       string
+	dir          string // Local copy
+	state        string // State file
+	staging      string // Copy of the last sync, until it is committed
+	stagingState string // State of the staging copy
+}
+
+// New returns the source of rawURL.
+func New(rawURL string, opts Options) (Source, error) {
+	if opts.CacheDir == "" {
+		return nil, fmt.Errorf("%w", ErrNoCacheDir)
+	}
+	if strings.HasPrefix(rawURL, gitPrefix) {
+		return newGitSource(rawURL, opts)
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse rule source %s: %w", rawURL, err)
+	}
+	switch parsed.Scheme {
+	case schemeS3:
+		return newS3Source(rawURL, parsed, opts)
+	case schemeHTTPS, schemeHTTP:
+		return newHTTPSource(rawURL, opts), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedSource, rawURL)
+	}
+}
+
+// newCacheEntry returns the location of rawURL in cacheDir. The name of the
+// directory is derived from the URL, so that changing a URL starts from scratch.
+func newCacheEntry(cacheDir, kind, rawURL string) cacheEntry {
+	sum := sha256.Sum256([]byte(rawURL))
+	name := kind + "-" + hex.EncodeToString(sum[:])[:16]
+	return cacheEntry{
+		rawURL:       rawURL,
+		dir:          filepath.Join(cacheDir, name),
+		state:        filepath.Join(cacheDir, name+".json"),
+		staging:      filepath.Join(cacheDir, name+".staging"),
+		stagingState: filepath.Join(cacheDir, name+".staging.json"),
+	}
+}
+
+// exists reports whether a local copy of the source is available.
+func (c cacheEntry) exists() bool {
+	return dirExists(c.dir)
+}
+
+// localDir returns the staging copy if there is one, the local copy otherwise.
+func (c cacheEntry) localDir() string {
+	if dirExists(c.staging) {
+		return c.staging
+	}
+	return c.dir
+}
+
+// tempDir creates a temporary directory next to the local copy, to be staged.
+func (c cacheEntry) tempDir() (string, error) {
+	if err := os.MkdirAll(filepath.Dir(c.dir), dirPerm); err != nil {
+		return "", fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	tmpDir, err := os.MkdirTemp(filepath.Dir(c.dir), "."+filepath.Base(c.dir)+".tmp")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	return tmpDir, nil
+}
+
+// load returns the saved state, an empty one if there is none or if the local copy is missing.
+func (c cacheEntry) load() state {
+	st := state{URL: c.rawURL}
+	if !c.exists() {
+		return st
+	}
+	// #nosec G304 - state file of the cache
+	data, err := os.ReadFile(c.state)
+	if err != nil {
+		return st
+	}
+	var saved state
+	if json.Unmarshal(data, &saved) != nil || saved.URL != c.rawURL {
+		return st
+	}
+	return saved
+}
+
+// save writes the state of the source.
+func (c cacheEntry) save(st state) error {
+	return saveState(c.state, st)
+}
+
+// stage makes tmpDir the staging copy, st being its state.
+func (c cacheEntry) stage(tmpDir string, st state) error {
+	// The staging copy exists once its state is written
+	if err := saveState(c.stagingState, st); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpDir, c.staging); err != nil {
+		return fmt.Errorf("failed to rename %s: %w", tmpDir, err)
+	}
+	return nil
+}
+
+// commit replaces the local copy and its state with the staging ones, if any.
+func (c cacheEntry) commit() error {
+	if !dirExists(c.staging) {
+		return nil
+	}
+	if err := c.replaceDir(c.staging); err != nil {
+		return err
+	}
+	if err := os.Rename(c.stagingState, c.state); err != nil {
+		return fmt.Errorf("failed to rename %s: %w", c.stagingState, err)
+	}
+	return nil
+}
+
+// discard removes the staging copy and its state, if any.
+func (c cacheEntry) discard() error {
+	if err := os.RemoveAll(c.staging); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", c.staging, err)
+	}
+	if err := os.Remove(c.stagingState); err != nil && !isNotExist(err) {
+		return fmt.Errorf("failed to remove %s: %w", c.stagingState, err)
+	}
+	return nil
+}
+
+// saveState writes st to filename.
+func saveState(filename string, st state) error {
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+	return writeFileAtomic(filename, data)
+}
+
+// replaceDir replaces the local copy with the content of tmpDir.
+func (c cacheEntry) replaceDir(tmpDir string) error {
+	if err := os.RemoveAll(c.dir); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", c.dir, err)
+	}
+	if err := os.Rename(tmpDir, c.dir); err != nil {
+		return fmt.Errorf("failed to rename %s: %w", tmpDir, err)
+	}
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file renamed to filename.
+func writeFileAtomic(filename string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(filename), dirPerm); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		return fmt.Errorf("failed to rename %s: %w", filename, err)
+	}
+	return nil
+}
+
+// copyDir copies the directories and regular files of src into dst.
+func copyDir(src, dst string) error {
+	err := filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err //nolint:wrapcheck // wrapped below
+		}
+		target := filepath.Join(dst, rel)
+		switch {
+		case entry.IsDir():
+			return os.MkdirAll(target, dirPerm) //nolint:wrapcheck // wrapped below
+		case entry.Type().IsRegular():
+			return copyFile(path, target)
+		default:
+			return nil // Links are restored by git
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy %s: %w", src, err)
+	}
+	return nil
+}
+
+// copyFile copies the content of src to dst.
+func copyFile(src, dst string) error {
+	// #nosec G304 - file of the cache
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", src, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), dirPerm); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.WriteFile(dst, data, filePerm); err != nil {
+		return fmt.Errorf("failed to write %s: %w", dst, err)
+	}
+	return nil
+}
+
+// localPath returns the path of name inside dir. Names escaping dir are refused.
+func localPath(dir, name string) (string, error) {
+	cleaned := filepath.Clean(filepath.FromSlash(strings.TrimLeft(name, "/")))
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: %s", ErrUnsafePath, name)
+	}
+	return filepath.Join(dir, cleaned), nil
+}
+
+// dirExists reports whether dir is an existing directory.
+func dirExists(dir string) bool {
+	info, err := os.Stat(dir)
+	return err == nil && info.IsDir()
+}
+
+// isNotExist reports whether err means that a file does not exist.
+func isNotExist(err error) bool {
+	return errors.Is(err, os.ErrNotExist)
+}
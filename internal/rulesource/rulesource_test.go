@@ -0,0 +1,349 @@
+package rulesource_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/md5" // #nosec G501 - ETag of the fake S3
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/sgaunet/awslogcheck/internal/rulesource"
+)
+
+func tarball(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		header := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(files[name])), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatalf("Setup failed: %v", err)
+		}
+		if _, err := tw.Write([]byte(files[name])); err != nil {
+			t.Fatalf("Setup failed: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func readRule(t *testing.T, dir, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatalf("Cannot read %s: %v", name, err)
+	}
+	return string(data)
+}
+
+func syncSource(t *testing.T, source rulesource.Source) bool {
+	t.Helper()
+	changed, err := source.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync returned error: %v", err)
+	}
+	if err := source.Commit(); err != nil {
+		t.Fatalf("Commit returned error: %v", err)
+	}
+	return changed
+}
+
+// TestHTTPSource tests the download of a tarball and the ETag tracking
+func TestHTTPSource(t *testing.T) {
+	var mu sync.Mutex
+	archive := tarball(t, map[string]string{"rules/app.rule": "^INFO:\n"})
+	etag := `"v1"`
+	downloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads++
+		w.Header().Set("ETag", etag)
+		_, _ = w.Write(archive)
+	}))
+	defer server.Close()
+
+	source, err := rulesource.New(server.URL+"/rules.tar.gz", rulesource.Options{CacheDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	if !syncSource(t, source) {
+		t.Error("First sync should report a change")
+	}
+	if got := readRule(t, source.Dir(), "rules/app.rule"); got != "^INFO:\n" {
+		t.Errorf("Unexpected rule file: %q", got)
+	}
+	if syncSource(t, source) {
+		t.Error("Sync should not report a change when the ETag is the same")
+	}
+
+	mu.Lock()
+	archive = tarball(t, map[string]string{"rules/app.rule": "^INFO:\n^DEBUG:\n"})
+	etag = `"v2"`
+	mu.Unlock()
+	if !syncSource(t, source) {
+		t.Error("Sync should report a change when the tarball changed")
+	}
+	if got := readRule(t, source.Dir(), "rules/app.rule"); got != "^INFO:\n^DEBUG:\n" {
+		t.Errorf("Unexpected rule file: %q", got)
+	}
+	if downloads != 2 {
+		t.Errorf("Expected 2 downloads, got %d", downloads)
+	}
+}
+
+// TestHTTPSourceDiscard tests that a discarded sync keeps the local copy, and is done again
+func TestHTTPSourceDiscard(t *testing.T) {
+	var mu sync.Mutex
+	archive := tarball(t, map[string]string{"app.rule": "^INFO:\n"})
+	etag := `"v1"`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		_, _ = w.Write(archive)
+	}))
+	defer server.Close()
+
+	source, err := rulesource.New(server.URL+"/rules.tar.gz", rulesource.Options{CacheDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	syncSource(t, source)
+
+	mu.Lock()
+	archive = tarball(t, map[string]string{"app.rule": "^INFO:\n^DEBUG:\n"})
+	etag = `"v2"`
+	mu.Unlock()
+	for range 2 {
+		changed, err := source.Sync(context.Background())
+		if err != nil || !changed {
+			t.Fatalf("Sync returned %v, %v", changed, err)
+		}
+		if got := readRule(t, source.Dir(), "app.rule"); got != "^INFO:\n^DEBUG:\n" {
+			t.Errorf("The staging copy should be used, got %q", got)
+		}
+		if err := source.Discard(); err != nil {
+			t.Fatalf("Discard returned error: %v", err)
+		}
+		if got := readRule(t, source.Dir(), "app.rule"); got != "^INFO:\n" {
+			t.Errorf("The local copy should be kept, got %q", got)
+		}
+	}
+}
+
+// TestHTTPSourceErrors tests HTTP errors and archives escaping the cache
+func TestHTTPSourceErrors(t *testing.T) {
+	archive := tarball(t, map[string]string{"../evil.rule": ".*\n"})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing.tar.gz" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(archive)
+	}))
+	defer server.Close()
+	cacheDir := t.TempDir()
+
+	source, err := rulesource.New(server.URL+"/missing.tar.gz", rulesource.Options{CacheDir: cacheDir})
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	if _, err := source.Sync(context.Background()); !errors.Is(err, rulesource.ErrUnexpectedStatus) {
+		t.Errorf("Expected ErrUnexpectedStatus, got %v", err)
+	}
+
+	source, err = rulesource.New(server.URL+"/evil.tar.gz", rulesource.Options{CacheDir: cacheDir})
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	if _, err := source.Sync(context.Background()); !errors.Is(err, rulesource.ErrUnsafePath) {
+		t.Errorf("Expected ErrUnsafePath, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(cacheDir, "evil.rule")); err == nil {
+		t.Error("File extracted outside of the cache")
+	}
+}
+
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %s failed: %v: %s", strings.Join(args, " "), err, out)
+	}
+}
+
+// TestGitSource tests the clone and the update of a local bare repository
+func TestGitSource(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	bare := filepath.Join(t.TempDir(), "rules.git")
+	work := t.TempDir()
+	git(t, "", "init", "--quiet", "--bare", "--initial-branch=main", bare)
+	git(t, work, "init", "--quiet", "--initial-branch=main")
+	commit := func(content string) {
+		if err := os.MkdirAll(filepath.Join(work, "awslogcheck"), 0o755); err != nil {
+			t.Fatalf("Setup failed: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(work, "awslogcheck", "app.rule"), []byte(content), 0o644); err != nil {
+			t.Fatalf("Setup failed: %v", err)
+		}
+		git(t, work, "add", "-A")
+		git(t, work, "commit", "--quiet", "-m", "rules")
+		git(t, work, "push", "--quiet", bare, "main")
+	}
+	commit("^INFO:\n")
+
+	source, err := rulesource.New("git+file://"+bare+"?ref=main&path=awslogcheck",
+		rulesource.Options{CacheDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	if !syncSource(t, source) {
+		t.Error("First sync should report a change")
+	}
+	if got := readRule(t, source.Dir(), "app.rule"); got != "^INFO:\n" {
+		t.Errorf("Unexpected rule file: %q", got)
+	}
+	if syncSource(t, source) {
+		t.Error("Sync should not report a change without new commit")
+	}
+
+	commit("^INFO:\n^DEBUG:\n")
+	if !syncSource(t, source) {
+		t.Error("Sync should report a change after a new commit")
+	}
+	if got := readRule(t, source.Dir(), "app.rule"); got != "^INFO:\n^DEBUG:\n" {
+		t.Errorf("Unexpected rule file: %q", got)
+	}
+}
+
+// mockS3 is an in-memory bucket.
+type mockS3 struct {
+	objects map[string]string
+	gets    int
+}
+
+func (m *mockS3) ListObjectsV2(_ context.Context, params *s3.ListObjectsV2Input,
+	_ ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	output := &s3.ListObjectsV2Output{}
+	for key, content := range m.objects {
+		if !strings.HasPrefix(key, aws.ToString(params.Prefix)) {
+			continue
+		}
+		sum := md5.Sum([]byte(content)) // #nosec G401
+		output.Contents = append(output.Contents, types.Object{
+			Key:  aws.String(key),
+			ETag: aws.String(`"` + hex.EncodeToString(sum[:]) + `"`),
+		})
+	}
+	return output, nil
+}
+
+func (m *mockS3) GetObject(_ context.Context, params *s3.GetObjectInput,
+	_ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	m.gets++
+	return &s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader(m.objects[aws.ToString(params.Key)]))}, nil
+}
+
+// TestS3Source tests the synchronization of an S3 prefix
+func TestS3Source(t *testing.T) {
+	bucket := &mockS3{objects: map[string]string{
+		"awslogcheck/app.rule":   "^INFO:\n",
+		"awslogcheck/k8s/a.rule": "^DEBUG:\n",
+		"awslogcheck/":           "",
+		"other/ignored.rule":     ".*\n",
+	}}
+	source, err := rulesource.New("s3://bucket/awslogcheck", rulesource.Options{CacheDir: t.TempDir(), S3: bucket})
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	if !syncSource(t, source) {
+		t.Error("First sync should report a change")
+	}
+	if got := readRule(t, source.Dir(), "k8s/a.rule"); got != "^DEBUG:\n" {
+		t.Errorf("Unexpected rule file: %q", got)
+	}
+	if bucket.gets != 2 {
+		t.Errorf("Expected 2 downloads, got %d", bucket.gets)
+	}
+	if syncSource(t, source) {
+		t.Error("Sync should not report a change when the ETags are the same")
+	}
+
+	bucket.objects["awslogcheck/app.rule"] = "^WARN:\n"
+	delete(bucket.objects, "awslogcheck/k8s/a.rule")
+	if !syncSource(t, source) {
+		t.Error("Sync should report a change")
+	}
+	if got := readRule(t, source.Dir(), "app.rule"); got != "^WARN:\n" {
+		t.Errorf("Unexpected rule file: %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(source.Dir(), "k8s", "a.rule")); err == nil {
+		t.Error("Deleted object should be removed")
+	}
+	if bucket.gets != 3 {
+		t.Errorf("Expected 3 downloads, got %d", bucket.gets)
+	}
+}
+
+// TestNewErrors tests the invalid sources
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+		opts rulesource.Options
+		err  error
+	}{
+		{"no cache dir", "https://example.com/rules.tar.gz", rulesource.Options{}, rulesource.ErrNoCacheDir},
+		{"unsupported scheme", "ftp://example.com/rules", rulesource.Options{CacheDir: "/tmp"}, rulesource.ErrUnsupportedSource},
+		{"s3 without client", "s3://bucket/rules", rulesource.Options{CacheDir: "/tmp"}, rulesource.ErrNoS3Client},
+		{"git path escape", "git+https://example.com/r.git?path=../..", rulesource.Options{CacheDir: "/tmp"}, rulesource.ErrUnsafePath},
+		{"git option as ref", "git+https://example.com/r.git?ref=--upload-pack=x", rulesource.Options{CacheDir: "/tmp"}, rulesource.ErrInvalidGitSource},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := rulesource.New(tt.url, tt.opts); !errors.Is(err, tt.err) {
+				t.Errorf("Expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package rulesource
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// S3API is the part of the S3 client used to synchronize s3:// sources.
+type S3API interface {
+	s3.ListObjectsV2APIClient
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+}
+
+// s3Source is the set of objects under an S3 prefix.
+type s3Source struct {
+	cache  cacheEntry
+	client S3API
+	bucket string
+	prefix string
+}
+
+func newS3Source(rawURL string, parsed *url.URL, opts Options) (*s3Source, error) {
+	if opts.S3 == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoS3Client, rawURL)
+	}
+	if parsed.Host == "" {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedSource, rawURL)
+	}
+	prefix := strings.TrimPrefix(parsed.Path, "/")
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return &s3Source{
+		cache:  newCacheEntry(opts.CacheDir, "s3", rawURL),
+		client: opts.S3,
+		bucket: parsed.Host,
+		prefix: prefix,
+	}, nil
+}
+
+// URL returns the URL of the source.
+func (s *s3Source) URL() string { return s.cache.rawURL }
+
+// Dir returns the directory of the local copy of the objects.
+func (s *s3Source) Dir() string { return s.cache.localDir() }
+
+// Commit replaces the local copy with the staging one, if any.
+func (s *s3Source) Commit() error { return s.cache.commit() }
+
+// Discard removes the staging copy, if any.
+func (s *s3Source) Discard() error { return s.cache.discard() }
+
+// Sync writes the objects in a staging copy if some of them changed: the ones whose ETag
+// changed are downloaded, the other ones are copied from the local copy.
+func (s *s3Source) Sync(ctx context.Context) (bool, error) {
+	if err := s.cache.discard(); err != nil {
+		return false, err
+	}
+	st := s.cache.load()
+	objects, err := s.list(ctx)
+	if err != nil {
+		return false, err
+	}
+	changed := len(objects) != len(st.Objects)
+	for key, etag := range objects {
+		if st.Objects[key] != etag || !s.cached(key) {
+			changed = true
+		}
+	}
+	if !changed {
+		return false, nil
+	}
+
+	tmpDir, err := s.cache.tempDir()
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	for key, etag := range objects {
+		name := strings.TrimPrefix(key, s.prefix)
+		target, err := localPath(tmpDir, name)
+		if err != nil {
+			return false, err
+		}
+		if st.Objects[key] == etag && s.cached(key) {
+			current, _ := localPath(s.cache.dir, name)
+			if err := copyFile(current, target); err != nil {
+				return false, err
+			}
+			continue
+		}
+		if err := s.download(ctx, key, target); err != nil {
+			return false, err
+		}
+	}
+	st.Objects = objects
+	return true, s.cache.stage(tmpDir, st)
+}
+
+// list returns the ETags of the objects under the prefix, by key.
+func (s *s3Source) list(ctx context.Context) (map[string]string, error) {
+	objects := make(map[string]string)
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(s.prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects of %s: %w", s.cache.rawURL, err)
+		}
+		for _, object := range page.Contents {
+			key := aws.ToString(object.Key)
+			if strings.HasSuffix(key, "/") {
+				continue // Folder placeholder
+			}
+			if _, err := localPath(s.cache.dir, strings.TrimPrefix(key, s.prefix)); err != nil {
+				return nil, err
+			}
+			objects[key] = aws.ToString(object.ETag)
+		}
+	}
+	return objects, nil
+}
+
+// cached reports whether the object of key is in the local copy.
+func (s *s3Source) cached(key string) bool {
+	target, err := localPath(s.cache.dir, strings.TrimPrefix(key, s.prefix))
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(target)
+	return err == nil
+}
+
+func (s *s3Source) download(ctx context.Context, key, target string) error {
+	output, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get s3://%s/%s: %w", s.bucket, key, err)
+	}
+	defer func() { _ = output.Body.Close() }()
+	data, err := io.ReadAll(io.LimitReader(output.Body, maxArchiveSize+1))
+	if err != nil {
+		return fmt.Errorf("failed to read s3://%s/%s: %w", s.bucket, key, err)
+	}
+	if len(data) > maxArchiveSize {
+		return fmt.Errorf("%w: s3://%s/%s", ErrArchiveTooLarge, s.bucket, key)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), dirPerm); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	return writeFileAtomic(target, data)
+}
@@ -23,7 +23,6 @@ import (
 )
 
 const (
-	ingestionTimeS = 120
 	lastPeriodSeconds = 3600
 	signalChannelSize = 5
 	exitWaitSeconds = 1
@@ -42,8 +41,8 @@ func checkErrorAndExitIfErr(err error, logger *slog.Logger) {
 }
 
 // print AWS identity.
-func printID(cfg aws.Config, logger *slog.Logger) {
-	client := sts.NewFromConfig(cfg)
+func printID(cfg aws.Config, stsEndpoint string, logger *slog.Logger) {
+	client := app.NewSTSClient(cfg, stsEndpoint)
 	identity, err := client.GetCallerIdentity(
 		context.TODO(),
 		&sts.GetCallerIdentityInput{},
@@ -58,6 +57,7 @@ var version = "development"
 var application *app.App
 var awsCfg aws.Config // Configuration to connect to AWS API
 var appCtx context.Context
+var configFile string // Configuration file, read again on reload
 
 func printVersion() {
 	fmt.Println(version)
@@ -89,29 +89,43 @@ func loadConfiguration(configFilename string, appLog *slog.Logger) configapp.App
 	return configApp
 }
 
-func setupAWSConfig(ssoProfile string, appLog *slog.Logger) {
+// defaultRegion is used when aws_region is not configured, and no region is found in the environment.
+const defaultRegion = "eu-west-3"
+
+func setupAWSConfig(ssoProfile string, configApp configapp.AppConfig, appLog *slog.Logger) {
 	var err error
-	if len(ssoProfile) == 0 {
-		awsCfg, err = config.LoadDefaultConfig(context.TODO(), config.WithRegion("eu-west-3"))
-		checkErrorAndExitIfErr(err, appLog)
-	} else {
-		awsCfg, err = config.LoadDefaultConfig(
-			context.TODO(),
-			config.WithSharedConfigProfile(ssoProfile),
-		)
-		checkErrorAndExitIfErr(err, appLog)
+	var optFns []func(*config.LoadOptions) error
+	if len(ssoProfile) != 0 {
+		optFns = append(optFns, config.WithSharedConfigProfile(ssoProfile))
+	}
+	if len(configApp.AwsRegion) != 0 {
+		optFns = append(optFns, config.WithRegion(configApp.AwsRegion))
+	}
+	awsCfg, err = config.LoadDefaultConfig(context.TODO(), optFns...)
+	checkErrorAndExitIfErr(err, appLog)
+	if awsCfg.Region == "" {
+		awsCfg.Region = defaultRegion
 	}
-	printID(awsCfg, appLog)
+	appLog.Debug("AWS region", slog.String("region", awsCfg.Region))
+	if configApp.SkipIdentityCheck {
+		appLog.Debug("AWS identity check skipped")
+		return
+	}
+	printID(awsCfg, configApp.Endpoints.STS, appLog)
 }
 
-func runCronMode(cancel context.CancelFunc) {
+func runCronMode(ctx context.Context, cancel context.CancelFunc, configFilename string,
+	configApp configapp.AppConfig, appLog *slog.Logger) {
 	sigs := make(chan os.Signal, signalChannelSize)
+	hup := make(chan os.Signal, 1)
 	c := cron.New()
 	if err := c.AddFunc("0 0 * * *", mainRoutine); err != nil {
 		log.Fatalf("Failed to add cron job: %v", err)
 	}
 	c.Start()
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(hup, syscall.SIGHUP)
+	go watchConfiguration(ctx, configFilename, configApp.ReloadInterval, hup, appLog)
 	<-sigs
 	cancel()
 	c.Stop()
@@ -128,20 +142,31 @@ func main() {
 		os.Exit(0)
 	}
 
+	if flag.NArg() > 0 {
+		os.Exit(runCommand(flag.Args(), configFilename, ssoProfile, appLog))
+	}
+
 	configApp := loadConfiguration(configFilename, appLog)
 	
 	appLog = initTrace(configApp.DebugLevel)
 	appLog.Info("Log level set", slog.String("level", configApp.DebugLevel))
-	appLog.Debug("Log group configured", slog.String("loggroup", configApp.LogGroup))
+	appLog.Debug("Log group configured", slog.String("loggroup", configApp.LogGroup),
+		slog.Any("loggroups", configApp.LogGroups))
 
 	appCtx = context.Background()
 	appCtx, cancel := context.WithCancel(appCtx)
 
-	setupAWSConfig(ssoProfile, appLog)
+	setupAWSConfig(ssoProfile, configApp, appLog)
 	
 	application = app.New(appCtx, configApp, awsCfg, lastPeriodSeconds, appLog)
+	configFile = configFilename
 
-	err := application.LoadRules()
+	if _, err := application.SyncRuleSources(appCtx); err != nil {
+		appLog.Error("error occurred", slog.String("error", err.Error()))
+		appLog.Error("Cannot sync rule sources...")
+		os.Exit(1)
+	}
+	err := application.LoadStartupRules()
 	if err != nil {
 		appLog.Error("error occurred", slog.String("error", err.Error()))
 		appLog.Error("Cannot load rules...")
@@ -154,21 +179,22 @@ func main() {
 		os.Exit(0)
 	}
 
-	runCronMode(cancel)
+	runCronMode(appCtx, cancel, configFilename, configApp, appLog)
 }
 
 func mainRoutine() {
-	time.Sleep(time.Duration(ingestionTimeS) * time.Second) // Wait the time for the ingestion time of logs
 	// if debug mode, launch goroutine to print memory stats
 	// if os.Getenv("DEBUGLEVEL") == "debug" {
 	// 	stop = make(chan interface{})
 	// 	go app.PrintMemoryStats(stop)
 	// }
-	application.GetLogger().Debug("Start Logcheck")
-	err := application.LogCheck(appCtx)
+	refreshRuleSources(appCtx, configFile)
+	checkApp := currentApplication()
+	checkApp.GetLogger().Debug("Start Logcheck")
+	err := checkApp.LogCheck(appCtx)
 	if err != nil {
-		application.GetLogger().Error(err.Error())
+		checkApp.GetLogger().Error(err.Error())
 		os.Exit(1)
 	}
-	application.GetLogger().Debug("End Logcheck")
+	checkApp.GetLogger().Debug("End Logcheck")
 }
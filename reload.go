@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sgaunet/awslogcheck/internal/app"
+	"github.com/sgaunet/awslogcheck/internal/configapp"
+)
+
+const defaultReloadIntervalSeconds = 30
+
+var applicationMu sync.RWMutex
+
+// currentApplication returns the application to use for the next check.
+func currentApplication() *app.App {
+	applicationMu.RLock()
+	defer applicationMu.RUnlock()
+	return application
+}
+
+// reloadApplication reads the configuration and the rules again and replaces
+// the application if they are valid. A running check keeps the previous ones.
+// The rule sources are synced first, unless syncSources is false because they
+// were just synced. AWS settings are not reloaded.
+func reloadApplication(configFilename string, syncSources bool) error {
+	configApp, err := configapp.ReadYamlCnxFile(configFilename)
+	if err != nil {
+		return fmt.Errorf("failed to read configuration: %w", err)
+	}
+	newApp := app.New(appCtx, configApp, awsCfg, lastPeriodSeconds, initTrace(configApp.DebugLevel))
+	if syncSources {
+		if _, err := newApp.SyncRuleSources(appCtx); err != nil {
+			return fmt.Errorf("failed to sync rule sources: %w", err)
+		}
+	}
+	if err := newApp.LoadSyncedRules(); err != nil {
+		return fmt.Errorf("failed to load rules: %w", err)
+	}
+	applicationMu.Lock()
+	application = newApp
+	applicationMu.Unlock()
+	return nil
+}
+
+// refreshRuleSources updates the remote rule sources before a check, and
+// reloads the application if one of them changed.
+func refreshRuleSources(ctx context.Context, configFilename string) {
+	checkApp := currentApplication()
+	changed, err := checkApp.SyncRuleSources(ctx)
+	if err != nil {
+		checkApp.GetLogger().Error("Cannot sync rule sources, keeping the previous rules",
+			slog.String("error", err.Error()))
+		return
+	}
+	if !changed {
+		return
+	}
+	// Staging copies of the sources removed from the configuration, if any
+	defer checkApp.DiscardRuleSources()
+	if err := reloadApplication(configFilename, false); err != nil {
+		checkApp.GetLogger().Error("Reload failed, keeping the previous configuration and rules",
+			slog.String("error", err.Error()))
+		return
+	}
+	checkApp.GetLogger().Info("Rule sources changed, rules reloaded")
+}
+
+// watchConfiguration reloads the application on SIGHUP, and when the configuration
+// or the rules change, until ctx is done.
+func watchConfiguration(ctx context.Context, configFilename string, reloadInterval int,
+	hup <-chan os.Signal, appLog *slog.Logger) {
+	if reloadInterval == 0 {
+		reloadInterval = defaultReloadIntervalSeconds
+	}
+	var tick <-chan time.Time
+	if reloadInterval > 0 {
+		ticker := time.NewTicker(time.Duration(reloadInterval) * time.Second)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	lastFingerprint, err := configapp.Fingerprint(configFilename)
+	if err != nil {
+		appLog.Error("Cannot fingerprint configuration", slog.String("error", err.Error()))
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			appLog.Info("SIGHUP received, reloading configuration and rules")
+			// The files reloaded are not a change for the next tick
+			if fingerprint, err := configapp.Fingerprint(configFilename); err == nil {
+				lastFingerprint = fingerprint
+			}
+		case <-tick:
+			fingerprint, err := configapp.Fingerprint(configFilename)
+			if err != nil {
+				appLog.Error("Cannot fingerprint configuration", slog.String("error", err.Error()))
+				continue
+			}
+			if fingerprint == lastFingerprint {
+				continue
+			}
+			// Broken files are reported once, not at every tick
+			lastFingerprint = fingerprint
+			appLog.Info("Configuration or rules changed, reloading")
+		}
+		if err := reloadApplication(configFilename, true); err != nil {
+			appLog.Error("Reload failed, keeping the previous configuration and rules",
+				slog.String("error", err.Error()))
+			continue
+		}
+		appLog.Info("Configuration and rules reloaded")
+	}
+}